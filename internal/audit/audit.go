@@ -0,0 +1,148 @@
+// Package audit records the mutating calls made to the metal server.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Syslog is the destination that sends entries to the system logger instead
+// of a file.
+const Syslog = "syslog"
+
+// Entry is one mutating call. Each is written as a single line of JSON.
+type Entry struct {
+	Time    time.Time       `json:"time"`
+	User    string          `json:"user"`
+	OSUser  string          `json:"os_user,omitempty"`
+	Context string          `json:"context,omitempty"`
+	Server  string          `json:"server"`
+	Command []string        `json:"command"`
+	RPC     string          `json:"rpc"`
+	Request json.RawMessage `json:"request,omitempty"`
+	Code    string          `json:"code"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// Log writes entries to a file or to syslog. User is the metal login, which
+// may be shared, and OSUser the local account running the command.
+type Log struct {
+	User    string
+	OSUser  string
+	Context string
+	Server  string
+	Command []string
+
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// mutating lists the prefixes of the RPCs that change the schema.
+var mutating = []string{"Create", "Update", "Delete"}
+
+// Open appends to the file dest, creating it if needed, or logs to syslog if
+// dest is Syslog.
+func Open(dest string) (*Log, error) {
+	if dest == Syslog {
+		w, err := openSyslog()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Log{w: w}, nil
+	}
+
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Log{w: f}, nil
+}
+
+func (l *Log) Close() error {
+	return l.w.Close()
+}
+
+// Interceptor records every Create, Update and Delete call after it returns,
+// along with its outcome. Other calls pass through untouched.
+func (l *Log) Interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		if !isMutating(method) {
+			return err
+		}
+
+		if werr := l.write(method, req, err); werr != nil {
+			return errors.Join(err, fmt.Errorf("audit log: %w", werr))
+		}
+
+		return err
+	}
+}
+
+func (l *Log) write(method string, req any, err error) error {
+	e := Entry{
+		Time:    time.Now().UTC(),
+		User:    l.User,
+		OSUser:  l.OSUser,
+		Context: l.Context,
+		Server:  l.Server,
+		Command: l.Command,
+		RPC:     path.Base(method),
+		Code:    status.Code(err).String(),
+	}
+
+	if m, ok := req.(proto.Message); ok {
+		b, merr := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+		if merr != nil {
+			return merr
+		}
+
+		e.Request = b
+	}
+
+	if err != nil {
+		e.Error = status.Convert(err).Message()
+	}
+
+	line, jerr := json.Marshal(e)
+	if jerr != nil {
+		return jerr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, werr := l.w.Write(append(line, '\n'))
+
+	return werr
+}
+
+func isMutating(method string) bool {
+	name := path.Base(method)
+
+	for _, prefix := range mutating {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
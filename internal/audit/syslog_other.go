@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package audit
+
+import (
+	"errors"
+	"io"
+)
+
+var errNoSyslog = errors.New("syslog is not supported on this platform")
+
+func openSyslog() (io.WriteCloser, error) {
+	return nil, errNoSyslog
+}
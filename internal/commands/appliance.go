@@ -4,8 +4,8 @@ import (
 	"github.com/spf13/cobra"
 
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
 	"endobit.io/stack/internal/flags/set"
-	"endobit.io/table"
 )
 
 type Appliance struct {
@@ -52,7 +52,12 @@ func (a *Appliance) Set() *cobra.Command {
 		Short: "Set an " + appliance + "'s properties",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return a.update(args[0])
+			done, err := a.journal(a.parent(), "appliances", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0]))
 		},
 	}
 
@@ -80,6 +85,7 @@ func (a *Appliance) List() *cobra.Command {
 	}
 
 	a.zone.Add(cmd.Flags(), appliance, false)
+	a.output.Add(cmd.Flags())
 
 	cmd.AddCommand(NewApplianceAttr(a).List())
 
@@ -98,6 +104,10 @@ func (a *Appliance) Remove() *cobra.Command {
 
 	a.zone.Add(cmd.Flags(), appliance, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), appliance)
+
 	cmd.AddCommand(NewApplianceAttr(a).Remove())
 
 	return cmd
@@ -116,8 +126,10 @@ func (a *Appliance) create(appliance string) error {
 
 func (a *Appliance) list(glob string) error {
 	type row struct{ Zone, Appliance string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(appliance, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewApplianceReader(a.zone.Val(), glob)
 
@@ -132,7 +144,7 @@ func (a *Appliance) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *Appliance) update(appliance string) error {
@@ -149,15 +161,39 @@ func (a *Appliance) update(appliance string) error {
 	return err
 }
 
+// parent is where the appliances are in the schema.
+func (a *Appliance) parent() diff.Path {
+	return path("zones", a.zone.Val())
+}
+
 func (a *Appliance) remove(glob string) error {
-	req := pb.DeleteAppliancesRequest_builder{
-		Zone: a.zone.Ptr(),
-		Glob: &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewApplianceReader(a.zone.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteAppliances(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(appliance, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "appliances", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteAppliancesRequest_builder{
+			Zone: a.zone.Ptr(),
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteAppliances(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
 
 func (a *ApplianceAttr) Add() *cobra.Command {
@@ -192,7 +228,12 @@ func (a *ApplianceAttr) Set() *cobra.Command {
 				value = args[1]
 			}
 
-			return a.update(args[0], value)
+			done, err := a.journal(a.parent(), "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
 		},
 	}
 
@@ -220,6 +261,7 @@ func (a *ApplianceAttr) List() *cobra.Command {
 
 	a.zone.Add(cmd.Flags(), appliance, false)
 	a.appliance.Add(cmd.Flags(), attribute, false)
+	a.output.Add(cmd.Flags())
 
 	return cmd
 }
@@ -237,6 +279,10 @@ func (a *ApplianceAttr) Remove() *cobra.Command {
 	a.zone.Add(cmd.Flags(), appliance, true)
 	a.appliance.Add(cmd.Flags(), attribute, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), appliance+" "+attribute)
+
 	return cmd
 }
 
@@ -254,8 +300,10 @@ func (a *ApplianceAttr) create(attr string) error {
 
 func (a *ApplianceAttr) list(glob string) error {
 	type row struct{ Zone, Appliance, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewApplianceAttrReader(a.zone.Val(), a.appliance.Val(), glob)
 
@@ -272,7 +320,7 @@ func (a *ApplianceAttr) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *ApplianceAttr) update(attr, val string) error {
@@ -297,14 +345,38 @@ func (a *ApplianceAttr) update(attr, val string) error {
 	return err
 }
 
+// parent is where the appliance attrs are in the schema.
+func (a *ApplianceAttr) parent() diff.Path {
+	return path("zones", a.zone.Val(), "appliances", a.appliance.Val())
+}
+
 func (a *ApplianceAttr) remove(glob string) error {
-	req := pb.DeleteApplianceAttrsRequest_builder{
-		Zone:      a.zone.Ptr(),
-		Appliance: a.appliance.Ptr(),
-		Glob:      &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewApplianceAttrReader(a.zone.Val(), a.appliance.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteApplianceAttrs(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(appliance+" "+attribute, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteApplianceAttrsRequest_builder{
+			Zone:      a.zone.Ptr(),
+			Appliance: a.appliance.Ptr(),
+			Glob:      &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteApplianceAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
@@ -0,0 +1,426 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
+)
+
+// objects maps the schema's repeated fields to the object type used in the
+// names of the metal requests.
+var objects = map[string]string{
+	"appliances":   "Appliance",
+	"attrs":        "Attr",
+	"clusters":     "Cluster",
+	"environments": "Environment",
+	"hosts":        "Host",
+	"interfaces":   "Interface",
+	"makes":        "Make",
+	"models":       "Model",
+	"networks":     "Network",
+	"racks":        "Rack",
+	"zones":        "Zone",
+}
+
+// dependencies lists the object lists in the order their objects are
+// created, so that whatever an object refers to, or is inside, exists first.
+var dependencies = []string{
+	"makes", "models",
+	"zones",
+	"racks", "appliances", "environments", "networks",
+	"clusters",
+	"hosts",
+	"interfaces", "attrs",
+}
+
+// apply reconciles the live schema with the one in the files. Objects missing
+// from the files are only deleted with --prune, and only within the zone and
+// cluster given, so --prune needs a zone.
+func (r *Root) apply(files []string) error {
+	if r.prune.Val() && !r.zone.IsSet() {
+		return errPruneZone
+	}
+
+	changes, err := r.changes(files)
+	if err != nil {
+		return err
+	}
+
+	return r.reconcile(changes, r.prune.Val())
+}
+
+// reconcile prints the changes and, unless this is a dry run, makes them.
+// Removed objects are deleted only if prune is set.
+func (r *Root) reconcile(changes []diff.Change, prune bool) error {
+	changes = r.planned(changes, prune)
+
+	if err := r.printChanges(changes); err != nil {
+		return err
+	}
+
+	if r.dryRun.Val() {
+		return nil
+	}
+
+	return r.execute(changes)
+}
+
+// planned drops the removed objects that will not be deleted: all of them
+// unless prune is set, and otherwise those outside the --zone and --cluster
+// given.
+func (r *Root) planned(changes []diff.Change, prune bool) []diff.Change {
+	return slices.DeleteFunc(slices.Clone(changes), func(c diff.Change) bool {
+		return c.Kind == diff.Removed && (!prune || !r.inScope(c.Path))
+	})
+}
+
+// execute makes the changes in dependency order.
+func (r *Root) execute(changes []diff.Change) error {
+	changes = diff.Order(diff.Expand(changes), dependencies)
+
+	var err error
+
+	for i := 0; i < len(changes); {
+		c := changes[i]
+
+		switch c.Kind {
+		case diff.Added:
+			err = r.send(c.Path, "Create", nil)
+			i++
+		case diff.Removed:
+			err = r.send(c.Path, "Delete", nil)
+			i++
+		case diff.Changed: // one update for all the object's fields
+			j := i + 1
+			for j < len(changes) && changes[j].Kind == diff.Changed && changes[j].Path.String() == c.Path.String() {
+				j++
+			}
+
+			err = r.send(c.Path, "Update", changes[i:j])
+			i = j
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// inScope reports whether path is within the --zone and --cluster given.
+func (r *Root) inScope(path diff.Path) bool {
+	if r.zone.IsSet() && path.Key("zones") != r.zone.Val() {
+		return false
+	}
+
+	if r.cluster.IsSet() && path.Key("clusters") != r.cluster.Val() {
+		return false
+	}
+
+	return true
+}
+
+// send builds the Create, Update or Delete request for the object at path
+// and calls metal with it. The request is found by name, so the object type
+// and its scope come from the path: the host h1 in cluster c1 of zone z1 is
+// created with a CreateHostRequest whose zone, cluster and name are z1, c1
+// and h1.
+func (r *Root) send(path diff.Path, op string, fields []diff.Change) error {
+	kind := object(path)
+	if kind == "" {
+		return fmt.Errorf("%w %s", errCannotApply, path)
+	}
+
+	name := op + kind + "Request"
+	if op == "Delete" {
+		name = op + kind + "sRequest"
+	}
+
+	pkg := (&pb.Schema{}).ProtoReflect().Descriptor().ParentFile().Package()
+
+	typ, err := protoregistry.GlobalTypes.FindMessageByName(pkg.Append(protoreflect.Name(name)))
+	if err != nil {
+		return fmt.Errorf("%w %s: %w", errCannotApply, strings.ToLower(op), err)
+	}
+
+	req := typ.New()
+
+	for _, s := range path[:len(path)-1] {
+		if o, ok := objects[s.Field]; ok {
+			_ = setField(req, strings.ToLower(o), s.Key) // not every request is scoped by every parent
+		}
+	}
+
+	key := path[len(path)-1].Key
+
+	switch op {
+	case "Delete":
+		err = setField(req, "glob", literal(key))
+	default:
+		err = setField(req, "name", key)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(fields) > 0 {
+		fd := req.Descriptor().Fields().ByName("fields")
+		if fd == nil {
+			fd = req.Descriptor().Fields().ByName("set")
+		}
+
+		if fd == nil {
+			return fmt.Errorf("%w %s fields", errCannotApply, kind)
+		}
+
+		set := req.Mutable(fd).Message()
+
+		for _, c := range fields {
+			if c.New == "" {
+				err = unsetField(req, c.Field)
+			} else {
+				err = setField(set, c.Field, c.New)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.call(req.Interface())
+}
+
+// object returns the request object type for the last step of path. Attrs
+// and interfaces take the type of their parent, and attrs are global at the
+// top of the schema.
+func object(path diff.Path) string {
+	if len(path) == 0 {
+		return ""
+	}
+
+	o := objects[path[len(path)-1].Field]
+	if o != "Attr" && o != "Interface" {
+		return o
+	}
+
+	if len(path) == 1 && o == "Attr" {
+		return "GlobalAttr"
+	}
+
+	if parent := objects[path[len(path)-2].Field]; parent != "" && parent != "Attr" {
+		return parent + o
+	}
+
+	return ""
+}
+
+// unsetField marks the named field in the unset message of the update
+// request req. Only requests with an unset message can clear a field.
+func unsetField(req protoreflect.Message, name string) error {
+	fd := req.Descriptor().Fields().ByName("unset")
+	if fd == nil || fd.Message() == nil {
+		return fmt.Errorf("%w unset %s of %s", errCannotApply, name, req.Descriptor().Name())
+	}
+
+	return setField(req.Mutable(fd).Message(), name, "true")
+}
+
+// setField parses text into the named field of m.
+func setField(m protoreflect.Message, name, text string) error {
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil || fd.IsList() || fd.IsMap() {
+		return fmt.Errorf("%w %s of %s", errCannotApply, name, m.Descriptor().Name())
+	}
+
+	var (
+		v   protoreflect.Value
+		err error
+	)
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(text)
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(text)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Uint32Kind:
+		var n uint64
+		n, err = strconv.ParseUint(text, 10, 32)
+		v = protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Int32Kind:
+		var n int64
+		n, err = strconv.ParseInt(text, 10, 32)
+		v = protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Uint64Kind:
+		var n uint64
+		n, err = strconv.ParseUint(text, 10, 64)
+		v = protoreflect.ValueOfUint64(n)
+	case protoreflect.Int64Kind:
+		var n int64
+		n, err = strconv.ParseInt(text, 10, 64)
+		v = protoreflect.ValueOfInt64(n)
+	case protoreflect.EnumKind:
+		ev := fd.Enum().Values().ByName(protoreflect.Name(text))
+		if ev == nil {
+			return fmt.Errorf("%w %s %q", errCannotApply, name, text)
+		}
+
+		v = protoreflect.ValueOfEnum(ev.Number())
+	default:
+		return fmt.Errorf("%w %s of %s", errCannotApply, name, m.Descriptor().Name())
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w %s: %w", errCannotApply, name, err)
+	}
+
+	m.Set(fd, v)
+
+	return nil
+}
+
+//nolint:gocyclo
+func (r *Root) call(req proto.Message) error {
+	ctx := r.Metal.Context()
+
+	var err error
+
+	switch req := req.(type) {
+	case *pb.CreateApplianceRequest:
+		_, err = r.Metal.CreateAppliance(ctx, req)
+	case *pb.UpdateApplianceRequest:
+		_, err = r.Metal.UpdateAppliance(ctx, req)
+	case *pb.DeleteAppliancesRequest:
+		_, err = r.Metal.DeleteAppliances(ctx, req)
+	case *pb.CreateApplianceAttrRequest:
+		_, err = r.Metal.CreateApplianceAttr(ctx, req)
+	case *pb.UpdateApplianceAttrRequest:
+		_, err = r.Metal.UpdateApplianceAttr(ctx, req)
+	case *pb.DeleteApplianceAttrsRequest:
+		_, err = r.Metal.DeleteApplianceAttrs(ctx, req)
+	case *pb.CreateClusterRequest:
+		_, err = r.Metal.CreateCluster(ctx, req)
+	case *pb.UpdateClusterRequest:
+		_, err = r.Metal.UpdateCluster(ctx, req)
+	case *pb.DeleteClustersRequest:
+		_, err = r.Metal.DeleteClusters(ctx, req)
+	case *pb.CreateClusterAttrRequest:
+		_, err = r.Metal.CreateClusterAttr(ctx, req)
+	case *pb.UpdateClusterAttrRequest:
+		_, err = r.Metal.UpdateClusterAttr(ctx, req)
+	case *pb.DeleteClusterAttrsRequest:
+		_, err = r.Metal.DeleteClusterAttrs(ctx, req)
+	case *pb.CreateEnvironmentRequest:
+		_, err = r.Metal.CreateEnvironment(ctx, req)
+	case *pb.UpdateEnvironmentRequest:
+		_, err = r.Metal.UpdateEnvironment(ctx, req)
+	case *pb.DeleteEnvironmentsRequest:
+		_, err = r.Metal.DeleteEnvironments(ctx, req)
+	case *pb.CreateEnvironmentAttrRequest:
+		_, err = r.Metal.CreateEnvironmentAttr(ctx, req)
+	case *pb.UpdateEnvironmentAttrRequest:
+		_, err = r.Metal.UpdateEnvironmentAttr(ctx, req)
+	case *pb.DeleteEnvironmentAttrsRequest:
+		_, err = r.Metal.DeleteEnvironmentAttrs(ctx, req)
+	case *pb.CreateGlobalAttrRequest:
+		_, err = r.Metal.CreateGlobalAttr(ctx, req)
+	case *pb.UpdateGlobalAttrRequest:
+		_, err = r.Metal.UpdateGlobalAttr(ctx, req)
+	case *pb.DeleteGlobalAttrsRequest:
+		_, err = r.Metal.DeleteGlobalAttrs(ctx, req)
+	case *pb.CreateHostRequest:
+		_, err = r.Metal.CreateHost(ctx, req)
+	case *pb.UpdateHostRequest:
+		_, err = r.Metal.UpdateHost(ctx, req)
+	case *pb.DeleteHostsRequest:
+		_, err = r.Metal.DeleteHosts(ctx, req)
+	case *pb.CreateHostAttrRequest:
+		_, err = r.Metal.CreateHostAttr(ctx, req)
+	case *pb.UpdateHostAttrRequest:
+		_, err = r.Metal.UpdateHostAttr(ctx, req)
+	case *pb.DeleteHostAttrsRequest:
+		_, err = r.Metal.DeleteHostAttrs(ctx, req)
+	case *pb.CreateHostInterfaceRequest:
+		_, err = r.Metal.CreateHostInterface(ctx, req)
+	case *pb.UpdateHostInterfaceRequest:
+		_, err = r.Metal.UpdateHostInterface(ctx, req)
+	case *pb.DeleteHostInterfacesRequest:
+		_, err = r.Metal.DeleteHostInterfaces(ctx, req)
+	case *pb.CreateMakeRequest:
+		_, err = r.Metal.CreateMake(ctx, req)
+	case *pb.UpdateMakeRequest:
+		_, err = r.Metal.UpdateMake(ctx, req)
+	case *pb.DeleteMakesRequest:
+		_, err = r.Metal.DeleteMakes(ctx, req)
+	case *pb.CreateMakeAttrRequest:
+		_, err = r.Metal.CreateMakeAttr(ctx, req)
+	case *pb.UpdateMakeAttrRequest:
+		_, err = r.Metal.UpdateMakeAttr(ctx, req)
+	case *pb.DeleteMakeAttrsRequest:
+		_, err = r.Metal.DeleteMakeAttrs(ctx, req)
+	case *pb.CreateModelRequest:
+		_, err = r.Metal.CreateModel(ctx, req)
+	case *pb.UpdateModelRequest:
+		_, err = r.Metal.UpdateModel(ctx, req)
+	case *pb.DeleteModelsRequest:
+		_, err = r.Metal.DeleteModels(ctx, req)
+	case *pb.CreateModelAttrRequest:
+		_, err = r.Metal.CreateModelAttr(ctx, req)
+	case *pb.UpdateModelAttrRequest:
+		_, err = r.Metal.UpdateModelAttr(ctx, req)
+	case *pb.DeleteModelAttrsRequest:
+		_, err = r.Metal.DeleteModelAttrs(ctx, req)
+	case *pb.CreateNetworkRequest:
+		_, err = r.Metal.CreateNetwork(ctx, req)
+	case *pb.UpdateNetworkRequest:
+		_, err = r.Metal.UpdateNetwork(ctx, req)
+	case *pb.DeleteNetworksRequest:
+		_, err = r.Metal.DeleteNetworks(ctx, req)
+	case *pb.CreateNetworkAttrRequest:
+		_, err = r.Metal.CreateNetworkAttr(ctx, req)
+	case *pb.UpdateNetworkAttrRequest:
+		_, err = r.Metal.UpdateNetworkAttr(ctx, req)
+	case *pb.DeleteNetworkAttrsRequest:
+		_, err = r.Metal.DeleteNetworkAttrs(ctx, req)
+	case *pb.CreateRackRequest:
+		_, err = r.Metal.CreateRack(ctx, req)
+	case *pb.UpdateRackRequest:
+		_, err = r.Metal.UpdateRack(ctx, req)
+	case *pb.DeleteRacksRequest:
+		_, err = r.Metal.DeleteRacks(ctx, req)
+	case *pb.CreateRackAttrRequest:
+		_, err = r.Metal.CreateRackAttr(ctx, req)
+	case *pb.UpdateRackAttrRequest:
+		_, err = r.Metal.UpdateRackAttr(ctx, req)
+	case *pb.DeleteRackAttrsRequest:
+		_, err = r.Metal.DeleteRackAttrs(ctx, req)
+	case *pb.CreateZoneRequest:
+		_, err = r.Metal.CreateZone(ctx, req)
+	case *pb.UpdateZoneRequest:
+		_, err = r.Metal.UpdateZone(ctx, req)
+	case *pb.DeleteZonesRequest:
+		_, err = r.Metal.DeleteZones(ctx, req)
+	case *pb.CreateZoneAttrRequest:
+		_, err = r.Metal.CreateZoneAttr(ctx, req)
+	case *pb.UpdateZoneAttrRequest:
+		_, err = r.Metal.UpdateZoneAttr(ctx, req)
+	case *pb.DeleteZoneAttrsRequest:
+		_, err = r.Metal.DeleteZoneAttrs(ctx, req)
+	default:
+		return fmt.Errorf("%w %T", errCannotApply, req)
+	}
+
+	return err
+}
@@ -1,10 +1,12 @@
 package commands
 
 import (
+	"os"
+	"strings"
+
 	"github.com/spf13/cobra"
 
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
-	"endobit.io/table"
 )
 
 type Attr struct {
@@ -17,15 +19,21 @@ func NewAttr(r *Root) *Attr {
 
 func (a *Attr) Add() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   attribute + " name",
+		Use:   attribute + " name value",
 		Short: "Add a global " + attribute,
-		Args:  cobra.ExactArgs(1),
+		Long:  "Add a global " + attribute + ". A value of @path is read from the file at path.",
+		Args:  cobra.ExactArgs(2),
 		RunE: func(_ *cobra.Command, args []string) error {
+			value, err := readValue(args[1])
+			if err != nil {
+				return err
+			}
+
 			if err := a.create(args[0]); err != nil {
 				return err
 			}
 
-			return a.update(args[0])
+			return a.update(args[0], value)
 		},
 	}
 
@@ -34,11 +42,28 @@ func (a *Attr) Add() *cobra.Command {
 
 func (a *Attr) Set() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   attribute + " name",
+		Use:   attribute + " name [value]",
 		Short: "Set a global " + attribute + "'s properties",
+		Long:  "Set a global " + attribute + "'s properties. A value of @path is read from the file at path.",
 		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return a.update(args[0])
+			var value string
+
+			if len(args) > 1 {
+				v, err := readValue(args[1])
+				if err != nil {
+					return err
+				}
+
+				value = v
+			}
+
+			done, err := a.journal(nil, "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
 		},
 	}
 
@@ -62,6 +87,8 @@ func (a *Attr) List() *cobra.Command {
 		},
 	}
 
+	a.output.Add(cmd.Flags())
+
 	return cmd
 }
 
@@ -75,6 +102,10 @@ func (a *Attr) Remove() *cobra.Command {
 		},
 	}
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), attribute)
+
 	return cmd
 }
 
@@ -90,8 +121,10 @@ func (a *Attr) create(attr string) error {
 
 func (a *Attr) list(glob string) error {
 	type row struct{ Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewGlobalAttrReader(glob)
 
@@ -106,14 +139,21 @@ func (a *Attr) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
-func (a *Attr) update(attr string) error {
+func (a *Attr) update(attr, val string) error {
+	var value *string
+
+	if val != "" {
+		value = &val
+	}
+
 	req := pb.UpdateGlobalAttrRequest_builder{
 		Name: &attr,
 		Fields: pb.UpdateGlobalAttrRequest_Fields_builder{
-			Name: a.rename.Ptr(),
+			Name:  a.rename.Ptr(),
+			Value: value,
 		}.Build(),
 	}.Build()
 
@@ -123,11 +163,46 @@ func (a *Attr) update(attr string) error {
 }
 
 func (a *Attr) remove(glob string) error {
-	req := pb.DeleteGlobalAttrsRequest_builder{
-		Glob: &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewGlobalAttrReader(glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteGlobalAttrs(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(attribute, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(nil, "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteGlobalAttrsRequest_builder{
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteGlobalAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
+}
+
+// readValue returns the value of an attr argument, which is the contents of
+// the file when given as @path.
+func readValue(arg string) (string, error) {
+	file, ok := strings.CutPrefix(arg, "@")
+	if !ok {
+		return arg, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
 }
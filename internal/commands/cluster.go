@@ -6,7 +6,7 @@ import (
 	"github.com/spf13/cobra"
 
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
-	"endobit.io/table"
+	"endobit.io/stack/internal/diff"
 )
 
 type Cluster struct {
@@ -52,7 +52,12 @@ func (a *Cluster) Set() *cobra.Command {
 		Short: "Set a " + cluster + "'s properties",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return a.update(args[0])
+			done, err := a.journal(a.parent(), "clusters", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0]))
 		},
 	}
 
@@ -83,6 +88,7 @@ func (a *Cluster) List() *cobra.Command {
 	}
 
 	a.zone.Add(cmd.Flags(), cluster, false)
+	a.output.Add(cmd.Flags())
 
 	cmd.AddCommand(NewClusterAttr(a).List())
 
@@ -101,6 +107,10 @@ func (a *Cluster) Remove() *cobra.Command {
 
 	a.zone.Add(cmd.Flags(), cluster, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), cluster)
+
 	cmd.AddCommand(NewClusterAttr(a).Remove())
 
 	return cmd
@@ -119,8 +129,10 @@ func (a *Cluster) create(cluster string) error {
 
 func (a *Cluster) list(glob string) error {
 	type row struct{ Zone, Cluster string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(cluster, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewClusterReader(a.zone.Val(), glob)
 
@@ -135,7 +147,7 @@ func (a *Cluster) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *Cluster) update(cluster string) error {
@@ -152,15 +164,39 @@ func (a *Cluster) update(cluster string) error {
 	return err
 }
 
+// parent is where the clusters are in the schema.
+func (a *Cluster) parent() diff.Path {
+	return path("zones", a.zone.Val())
+}
+
 func (a *Cluster) remove(glob string) error {
-	req := pb.DeleteClustersRequest_builder{
-		Zone: a.zone.Ptr(),
-		Glob: &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewClusterReader(a.zone.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteClusters(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(cluster, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "clusters", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteClustersRequest_builder{
+			Zone: a.zone.Ptr(),
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteClusters(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
 
 func (a *ClusterAttr) Add() *cobra.Command {
@@ -195,7 +231,12 @@ func (a *ClusterAttr) Set() *cobra.Command {
 				value = args[1]
 			}
 
-			return a.update(args[0], value)
+			done, err := a.journal(a.parent(), "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
 		},
 	}
 
@@ -223,6 +264,7 @@ func (a *ClusterAttr) List() *cobra.Command {
 
 	a.zone.Add(cmd.Flags(), cluster, false)
 	a.cluster.Add(cmd.Flags(), attribute, false)
+	a.output.Add(cmd.Flags())
 
 	return cmd
 }
@@ -240,6 +282,10 @@ func (a *ClusterAttr) Remove() *cobra.Command {
 	a.zone.Add(cmd.Flags(), cluster, true)
 	a.cluster.Add(cmd.Flags(), attribute, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), cluster+" "+attribute)
+
 	return cmd
 }
 
@@ -257,8 +303,10 @@ func (a *ClusterAttr) create(attr string) error {
 
 func (a *ClusterAttr) list(glob string) error {
 	type row struct{ Zone, Cluster, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewClusterAttrReader(a.zone.Val(), a.cluster.Val(), glob)
 
@@ -275,7 +323,7 @@ func (a *ClusterAttr) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *ClusterAttr) update(attr, val string) error {
@@ -300,14 +348,38 @@ func (a *ClusterAttr) update(attr, val string) error {
 	return err
 }
 
+// parent is where the cluster attrs are in the schema.
+func (a *ClusterAttr) parent() diff.Path {
+	return path("zones", a.zone.Val(), "clusters", a.cluster.Val())
+}
+
 func (a *ClusterAttr) remove(glob string) error {
-	req := pb.DeleteClusterAttrsRequest_builder{
-		Zone:    a.zone.Ptr(),
-		Cluster: a.cluster.Ptr(),
-		Glob:    &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewClusterAttrReader(a.zone.Val(), a.cluster.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteClusterAttrs(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(cluster+" "+attribute, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteClusterAttrsRequest_builder{
+			Zone:    a.zone.Ptr(),
+			Cluster: a.cluster.Ptr(),
+			Glob:    &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteClusterAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
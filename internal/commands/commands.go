@@ -8,13 +8,22 @@ type Verb int
 
 const (
 	Add Verb = iota
+	Apply
+	Config
+	Diff
 	Dump
 	List
 	Load
+	Login
+	Logout
 	Remove
 	Report
+	Schema
 	Set
+	Snapshot
+	Undo
 	Unset
+	Validate
 )
 
 const (
@@ -23,13 +32,31 @@ const (
 	appliance   = "appliance"
 	cluster     = "cluster"
 	host        = "host"
+	iface       = "interface"
 	environment = "environment"
+	vendor      = "make"
 	model       = "model"
+	network     = "network"
 	zone        = "zone"
 )
 
 var (
+	errCannotApply        = errors.New("cannot apply")
+	errInvalidGateway     = errors.New("invalid gateway")
 	errInvalidHostType    = errors.New("invalid host type")
+	errInvalidIP          = errors.New("invalid IP address")
+	errInvalidMAC         = errors.New("invalid MAC address")
+	errInvalidSubnet      = errors.New("invalid subnet")
+	errInvalidVLAN        = errors.New("invalid VLAN")
+	errMergeJSON          = errors.New("comments can only be merged into YAML")
 	errMissingClusterZone = errors.New("cluster zone not specified")
 	errMissingMakeOrModel = errors.New("if either make or model is specified, both must be set")
+	errNotConfirmed       = errors.New("not confirmed, use --yes when stdin is not a terminal")
+	errNotInSnapshot      = errors.New("not in snapshot")
+	errOtherServer        = errors.New("made on another server, use --force")
+	errOverlap            = errors.New("overlapping networks")
+	errPruneZone          = errors.New("--prune needs --zone")
+	errSchemaDiffers      = errors.New("schema differs")
+	errTooMany            = errors.New("too many matches, use --force")
+	errUnknownNetwork     = errors.New("unknown network")
 )
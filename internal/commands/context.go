@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"endobit.io/stack/internal/config"
+	"endobit.io/stack/internal/flags"
+	"endobit.io/stack/internal/flags/set"
+	"endobit.io/table"
+)
+
+type Context struct {
+	*Root
+	passEnv     set.PassEnv
+	passCommand set.PassCommand
+}
+
+func NewContext(r *Root) *Context {
+	return &Context{Root: r}
+}
+
+func (c *Context) Use() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use-context name",
+		Short: "Set the current context",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := c.Config.UseContext(args[0]); err != nil {
+				return err
+			}
+
+			return c.Config.Save()
+		},
+	}
+
+	return cmd
+}
+
+func (c *Context) List() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get-contexts",
+		Short: "List the configured contexts",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return c.list()
+		},
+	}
+
+	return cmd
+}
+
+func (c *Context) Set() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-context name",
+		Short: "Add or modify a context",
+		Long: "Add or modify a context. The --" + flags.Metal + ", --" + flags.Mops +
+			", --" + flags.MetalUser + ", --" + flags.AuditLog + " and TLS flags set the context's servers, " +
+			"user, audit log and transport security.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.set(cmd, args[0])
+		},
+	}
+
+	c.zone.Add(cmd.Flags(), "context", false)
+	c.passEnv.Add(cmd.Flags(), "context")
+	c.passCommand.Add(cmd.Flags(), "context")
+
+	return cmd
+}
+
+func (c *Context) list() error {
+	type row struct {
+		Current string `table:",omitempty"`
+		Context string
+		Metal   string
+		Mops    string
+		User    string
+		Zone    string
+	}
+
+	t := table.New()
+	defer t.Flush()
+
+	for _, ctx := range c.Config.Contexts {
+		var current string
+
+		if ctx.Name == c.Config.CurrentContext {
+			current = "*"
+		}
+
+		_ = t.Write(row{
+			Current: current,
+			Context: ctx.Name,
+			Metal:   ctx.Metal,
+			Mops:    ctx.Mops,
+			User:    ctx.User,
+			Zone:    ctx.Zone,
+		})
+	}
+
+	return nil
+}
+
+func (c *Context) set(cmd *cobra.Command, name string) error {
+	ctx := config.Context{Name: name}
+
+	if cur, err := c.Config.Context(name); err == nil {
+		ctx = *cur
+	}
+
+	fs := cmd.Flags()
+
+	if fs.Changed(flags.Metal) {
+		ctx.Metal, _ = fs.GetString(flags.Metal)
+	}
+
+	if fs.Changed(flags.Mops) {
+		ctx.Mops, _ = fs.GetString(flags.Mops)
+	}
+
+	if fs.Changed(flags.MetalUser) {
+		ctx.User, _ = fs.GetString(flags.MetalUser)
+	}
+
+	if fs.Changed(flags.CACert) {
+		ctx.TLS.CACert, _ = fs.GetString(flags.CACert)
+	}
+
+	if fs.Changed(flags.ClientCert) {
+		ctx.TLS.ClientCert, _ = fs.GetString(flags.ClientCert)
+	}
+
+	if fs.Changed(flags.ClientKey) {
+		ctx.TLS.ClientKey, _ = fs.GetString(flags.ClientKey)
+	}
+
+	if fs.Changed(flags.ServerName) {
+		ctx.TLS.ServerName, _ = fs.GetString(flags.ServerName)
+	}
+
+	if fs.Changed(flags.Insecure) {
+		ctx.TLS.Insecure, _ = fs.GetBool(flags.Insecure)
+	}
+
+	if fs.Changed(flags.AuditLog) {
+		ctx.AuditLog, _ = fs.GetString(flags.AuditLog)
+	}
+
+	if fs.Changed(flags.Zone) {
+		ctx.Zone = c.zone.Val()
+	}
+
+	if c.passEnv.IsSet() {
+		ctx.Credential = config.Credential{Env: c.passEnv.Val()}
+	}
+
+	if c.passCommand.IsSet() {
+		ctx.Credential = config.Credential{Command: c.passCommand.Val()}
+	}
+
+	c.Config.SetContext(ctx)
+
+	if c.Config.CurrentContext == "" {
+		c.Config.CurrentContext = name
+	}
+
+	return c.Config.Save()
+}
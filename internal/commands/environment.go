@@ -4,8 +4,8 @@ import (
 	"github.com/spf13/cobra"
 
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
 	"endobit.io/stack/internal/flags/set"
-	"endobit.io/table"
 )
 
 type Environment struct {
@@ -52,7 +52,12 @@ func (a *Environment) Set() *cobra.Command {
 		Short: "Set an " + environment + "'s properties",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return a.update(args[0])
+			done, err := a.journal(a.parent(), "environments", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0]))
 		},
 	}
 
@@ -80,6 +85,7 @@ func (a *Environment) List() *cobra.Command {
 	}
 
 	a.zone.Add(cmd.Flags(), environment, false)
+	a.output.Add(cmd.Flags())
 
 	cmd.AddCommand(NewEnvironmentAttr(a).List())
 
@@ -98,6 +104,10 @@ func (a *Environment) Remove() *cobra.Command {
 
 	a.zone.Add(cmd.Flags(), environment, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), environment)
+
 	cmd.AddCommand(NewEnvironmentAttr(a).Remove())
 
 	return cmd
@@ -116,8 +126,10 @@ func (a *Environment) create(environment string) error {
 
 func (a *Environment) list(glob string) error {
 	type row struct{ Zone, Environment string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(environment, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewEnvironmentReader(a.zone.Val(), glob)
 
@@ -132,7 +144,7 @@ func (a *Environment) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *Environment) update(environment string) error {
@@ -149,15 +161,39 @@ func (a *Environment) update(environment string) error {
 	return err
 }
 
+// parent is where the environments are in the schema.
+func (a *Environment) parent() diff.Path {
+	return path("zones", a.zone.Val())
+}
+
 func (a *Environment) remove(glob string) error {
-	req := pb.DeleteEnvironmentsRequest_builder{
-		Zone: a.zone.Ptr(),
-		Glob: &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewEnvironmentReader(a.zone.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteEnvironments(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(environment, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "environments", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteEnvironmentsRequest_builder{
+			Zone: a.zone.Ptr(),
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteEnvironments(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
 
 func (a *EnvironmentAttr) Add() *cobra.Command {
@@ -192,7 +228,12 @@ func (a *EnvironmentAttr) Set() *cobra.Command {
 				value = args[1]
 			}
 
-			return a.update(args[0], value)
+			done, err := a.journal(a.parent(), "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
 		},
 	}
 
@@ -220,6 +261,7 @@ func (a *EnvironmentAttr) List() *cobra.Command {
 
 	a.zone.Add(cmd.Flags(), environment, false)
 	a.environment.Add(cmd.Flags(), attribute, false)
+	a.output.Add(cmd.Flags())
 
 	return cmd
 }
@@ -237,6 +279,10 @@ func (a *EnvironmentAttr) Remove() *cobra.Command {
 	a.zone.Add(cmd.Flags(), environment, true)
 	a.environment.Add(cmd.Flags(), attribute, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), environment+" "+attribute)
+
 	return cmd
 }
 
@@ -254,8 +300,10 @@ func (a *EnvironmentAttr) create(attr string) error {
 
 func (a *EnvironmentAttr) list(glob string) error {
 	type row struct{ Zone, Environment, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewEnvironmentAttrReader(a.zone.Val(), a.environment.Val(), glob)
 
@@ -272,7 +320,7 @@ func (a *EnvironmentAttr) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *EnvironmentAttr) update(attr, val string) error {
@@ -297,14 +345,38 @@ func (a *EnvironmentAttr) update(attr, val string) error {
 	return err
 }
 
+// parent is where the environment attrs are in the schema.
+func (a *EnvironmentAttr) parent() diff.Path {
+	return path("zones", a.zone.Val(), "environments", a.environment.Val())
+}
+
 func (a *EnvironmentAttr) remove(glob string) error {
-	req := pb.DeleteEnvironmentAttrsRequest_builder{
-		Zone:        a.zone.Ptr(),
-		Environment: a.environment.Ptr(),
-		Glob:        &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewEnvironmentAttrReader(a.zone.Val(), a.environment.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteEnvironmentAttrs(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(environment+" "+attribute, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteEnvironmentAttrsRequest_builder{
+			Zone:        a.zone.Ptr(),
+			Environment: a.environment.Ptr(),
+			Glob:        &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteEnvironmentAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
@@ -1,15 +1,21 @@
 package commands
 
 import (
+	"cmp"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"endobit.io/metal"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
 	"endobit.io/stack/internal/flags/set"
 	"endobit.io/stack/internal/flags/unset"
-	"endobit.io/table"
 )
 
 type Host struct {
@@ -38,6 +44,21 @@ func NewHostAttr(h *Host) *HostAttr {
 	return &HostAttr{Host: h}
 }
 
+type HostInterface struct {
+	*Host
+	host      set.Host
+	mac       set.MAC
+	ip        set.IP
+	network   set.Network
+	bond      set.Bond
+	vlan      set.VLAN
+	isDefault set.Default
+}
+
+func NewHostInterface(h *Host) *HostInterface {
+	return &HostInterface{Host: h}
+}
+
 func (h *Host) Add() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   host + " name",
@@ -55,7 +76,9 @@ func (h *Host) Add() *cobra.Command {
 	h.zone.Add(cmd.Flags(), host, true)
 	h.cluster.Add(cmd.Flags(), host, false)
 
-	cmd.AddCommand(NewHostAttr(h).Add())
+	cmd.AddCommand(
+		NewHostAttr(h).Add(),
+		NewHostInterface(h).Add())
 
 	return cmd
 }
@@ -79,7 +102,12 @@ func (h *Host) Set() *cobra.Command {
 			h.rank.AcceptZero(cmd.Flags())
 			h.slot.AcceptZero(cmd.Flags())
 
-			return h.update(args[0])
+			done, err := h.journal(h.parent(), "hosts", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(h.update(args[0]))
 		},
 	}
 
@@ -97,7 +125,9 @@ func (h *Host) Set() *cobra.Command {
 	h.slot.Add(cmd.Flags(), host)
 	h.hostType.Add(cmd.Flags(), host)
 
-	cmd.AddCommand(NewHostAttr(h).Set())
+	cmd.AddCommand(
+		NewHostAttr(h).Set(),
+		NewHostInterface(h).Set())
 
 	return cmd
 }
@@ -126,6 +156,11 @@ func (h *Host) Unset() *cobra.Command {
 				m = Ptr(true) // make/model are set together, so unset together
 			}
 
+			done, err := h.journal(h.parent(), "hosts", args[0])
+			if err != nil {
+				return err
+			}
+
 			req := pb.UpdateHostRequest_builder{
 				Zone:    h.zone.Ptr(),
 				Cluster: h.cluster.Ptr(),
@@ -143,9 +178,9 @@ func (h *Host) Unset() *cobra.Command {
 				}.Build(),
 			}.Build()
 
-			_, err := h.Metal.UpdateHost(h.Metal.Context(), req)
+			_, err = h.Metal.UpdateHost(h.Metal.Context(), req)
 
-			return err
+			return done(err)
 
 		},
 	}
@@ -186,8 +221,11 @@ func (h *Host) List() *cobra.Command {
 
 	h.zone.Add(cmd.Flags(), host, false)
 	h.cluster.Add(cmd.Flags(), host, false)
+	h.output.Add(cmd.Flags())
 
-	cmd.AddCommand(NewHostAttr(h).List())
+	cmd.AddCommand(
+		NewHostAttr(h).List(),
+		NewHostInterface(h).List())
 
 	return cmd
 }
@@ -205,7 +243,13 @@ func (h *Host) Remove() *cobra.Command {
 	h.zone.Add(cmd.Flags(), host, true)
 	h.cluster.Add(cmd.Flags(), host, false)
 
-	cmd.AddCommand(NewHostAttr(h).Remove())
+	h.dryRun.Add(cmd.Flags())
+	h.yes.Add(cmd.Flags())
+	h.force.Add(cmd.Flags(), host)
+
+	cmd.AddCommand(
+		NewHostAttr(h).Remove(),
+		NewHostInterface(h).Remove())
 
 	return cmd
 }
@@ -237,8 +281,10 @@ func (h *Host) list(glob string) error {
 		Type        string `table:",omitempty"`
 	}
 
-	t := table.New()
-	defer t.Flush()
+	t, err := h.newWriter(host, row{})
+	if err != nil {
+		return err
+	}
 
 	r := h.Metal.NewHostReader(h.zone.Val(), h.cluster.Val(), glob)
 
@@ -275,7 +321,7 @@ func (h *Host) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (h *Host) update(host string) error {
@@ -308,16 +354,40 @@ func (h *Host) update(host string) error {
 	return err
 }
 
+// parent is where the hosts are in the schema.
+func (h *Host) parent() diff.Path {
+	return path("zones", h.zone.Val(), "clusters", h.cluster.Val())
+}
+
 func (h *Host) remove(glob string) error {
-	req := pb.DeleteHostsRequest_builder{
-		Zone:    h.zone.Ptr(),
-		Cluster: h.cluster.Ptr(),
-		Glob:    &glob,
-	}.Build()
+	names, err := matches(h.Metal.NewHostReader(h.zone.Val(), h.cluster.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := h.Metal.DeleteHosts(h.Metal.Context(), req)
+	if ok, err := h.confirmRemove(host, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := h.journal(h.parent(), "hosts", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteHostsRequest_builder{
+			Zone:    h.zone.Ptr(),
+			Cluster: h.cluster.Ptr(),
+			Glob:    &glob,
+		}.Build()
+
+		if _, err := h.Metal.DeleteHosts(h.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
 
 func (a *HostAttr) Add() *cobra.Command {
@@ -353,7 +423,12 @@ func (a *HostAttr) Set() *cobra.Command {
 				value = args[1]
 			}
 
-			return a.update(args[0], value)
+			done, err := a.journal(a.parent(), "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
 		},
 	}
 
@@ -383,6 +458,7 @@ func (a *HostAttr) List() *cobra.Command {
 	a.zone.Add(cmd.Flags(), host, false)
 	a.cluster.Add(cmd.Flags(), host, false)
 	a.host.Add(cmd.Flags(), attribute, false)
+	a.output.Add(cmd.Flags())
 
 	return cmd
 }
@@ -401,6 +477,10 @@ func (a *HostAttr) Remove() *cobra.Command {
 	a.cluster.Add(cmd.Flags(), host, false)
 	a.host.Add(cmd.Flags(), attribute, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), host+" "+attribute)
+
 	return cmd
 }
 
@@ -419,8 +499,10 @@ func (a *HostAttr) create(attr string) error {
 
 func (a *HostAttr) list(glob string) error {
 	type row struct{ Zone, Cluster, Host, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewHostAttrReader(a.zone.Val(), a.cluster.Val(), a.host.Val(), glob)
 
@@ -438,7 +520,7 @@ func (a *HostAttr) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *HostAttr) update(attr, val string) error {
@@ -464,15 +546,397 @@ func (a *HostAttr) update(attr, val string) error {
 	return err
 }
 
+// parent is where the host attrs are in the schema.
+func (a *HostAttr) parent() diff.Path {
+	return path("zones", a.zone.Val(), "clusters", a.cluster.Val(), "hosts", a.host.Val())
+}
+
 func (a *HostAttr) remove(glob string) error {
-	req := pb.DeleteHostAttrsRequest_builder{
+	names, err := matches(a.Metal.NewHostAttrReader(a.zone.Val(), a.cluster.Val(), a.host.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	if ok, err := a.confirmRemove(host+" "+attribute, names); !ok || err != nil {
+		return err
+	}
+
+	done, err := a.journal(a.parent(), "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteHostAttrsRequest_builder{
+			Zone:    a.zone.Ptr(),
+			Cluster: a.cluster.Ptr(),
+			Host:    a.host.Ptr(),
+			Glob:    &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteHostAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
+}
+
+func (a *HostInterface) Add() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   iface + " name",
+		Short: "Add an " + iface + " to a " + host,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a.vlan.AcceptZero(cmd.Flags())
+
+			if err := a.validate(args[0]); err != nil {
+				return err
+			}
+
+			if err := a.create(args[0]); err != nil {
+				return err
+			}
+
+			return a.update(args[0])
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), host, true)
+	a.cluster.Add(cmd.Flags(), host, false)
+	a.host.Add(cmd.Flags(), iface, true)
+	a.addFlags(cmd)
+
+	return cmd
+}
+
+func (a *HostInterface) Set() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   iface + " name",
+		Short: "Set a " + host + " " + iface + "'s properties",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a.vlan.AcceptZero(cmd.Flags())
+			a.isDefault.AcceptZero(cmd.Flags())
+
+			if err := a.validate(args[0]); err != nil {
+				return err
+			}
+
+			done, err := a.journal(a.parent(), "interfaces", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0]))
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), host, true)
+	a.cluster.Add(cmd.Flags(), host, false)
+	a.host.Add(cmd.Flags(), iface, true)
+	a.rename.Add(cmd.Flags(), iface)
+	a.addFlags(cmd)
+
+	return cmd
+}
+
+func (a *HostInterface) List() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   iface + " [glob]",
+		Short: "List one or more " + host + " " + iface + "s",
+		Long:  "List shows the " + iface + "s grouped by " + host + ".",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var glob string
+
+			if len(args) > 0 {
+				glob = args[0]
+			}
+			return a.list(glob)
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), host, false)
+	a.cluster.Add(cmd.Flags(), host, false)
+	a.host.Add(cmd.Flags(), iface, false)
+	a.output.Add(cmd.Flags())
+
+	return cmd
+}
+
+func (a *HostInterface) Remove() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   iface + " glob",
+		Short: "Remove one or more " + host + " " + iface + "s",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return a.remove(args[0])
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), host, true)
+	a.cluster.Add(cmd.Flags(), host, false)
+	a.host.Add(cmd.Flags(), iface, true)
+
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), host+" "+iface)
+
+	return cmd
+}
+
+func (a *HostInterface) addFlags(cmd *cobra.Command) {
+	a.mac.Add(cmd.Flags(), iface)
+	a.ip.Add(cmd.Flags(), iface)
+	a.network.Add(cmd.Flags(), iface, false)
+	a.bond.Add(cmd.Flags(), iface)
+	a.vlan.Add(cmd.Flags(), iface)
+	a.isDefault.Add(cmd.Flags(), iface)
+}
+
+func (a *HostInterface) create(name string) error {
+	req := pb.CreateHostInterfaceRequest_builder{
 		Zone:    a.zone.Ptr(),
 		Cluster: a.cluster.Ptr(),
 		Host:    a.host.Ptr(),
-		Glob:    &glob,
+		Name:    &name,
 	}.Build()
 
-	_, err := a.Metal.DeleteHostAttrs(a.Metal.Context(), req)
+	_, err := a.Metal.CreateHostInterface(a.Metal.Context(), req)
 
 	return err
 }
+
+func (a *HostInterface) list(glob string) error {
+	type row struct {
+		Zone      string
+		Cluster   string `table:",omitempty"`
+		Host      string
+		Interface string
+		MAC       string
+		IP        string
+		Network   string
+		Bond      string `table:",omitempty"`
+		VLAN      string `table:",omitempty"`
+		Default   string `table:",omitempty"`
+	}
+
+	t, err := a.newWriter(iface, row{})
+	if err != nil {
+		return err
+	}
+
+	var rows []row
+
+	r := a.Metal.NewHostInterfaceReader(a.zone.Val(), a.cluster.Val(), a.host.Val(), glob)
+
+	for resp, err := range r.Responses() {
+		if err != nil {
+			return err
+		}
+
+		var vlan, isDefault string
+
+		if resp.HasVlan() {
+			vlan = strconv.Itoa(int(resp.GetVlan()))
+		}
+		if resp.GetDefault() {
+			isDefault = "yes"
+		}
+
+		rows = append(rows, row{
+			Zone:      resp.GetZone(),
+			Cluster:   resp.GetCluster(),
+			Host:      resp.GetHost(),
+			Interface: resp.GetName(),
+			MAC:       resp.GetMac(),
+			IP:        resp.GetIp(),
+			Network:   resp.GetNetwork(),
+			Bond:      resp.GetBond(),
+			VLAN:      vlan,
+			Default:   isDefault,
+		})
+	}
+
+	// keep each host's interfaces together
+	slices.SortStableFunc(rows, func(x, y row) int {
+		return cmp.Or(
+			strings.Compare(x.Zone, y.Zone),
+			strings.Compare(x.Cluster, y.Cluster),
+			strings.Compare(x.Host, y.Host),
+			strings.Compare(x.Interface, y.Interface))
+	})
+
+	for _, row := range rows {
+		_ = t.Write(row)
+	}
+
+	return t.Flush()
+}
+
+func (a *HostInterface) update(name string) error {
+	req := pb.UpdateHostInterfaceRequest_builder{
+		Zone:    a.zone.Ptr(),
+		Cluster: a.cluster.Ptr(),
+		Host:    a.host.Ptr(),
+		Name:    &name,
+		Fields: pb.UpdateHostInterfaceRequest_Fields_builder{
+			Name:    a.rename.Ptr(),
+			Mac:     a.mac.Ptr(),
+			Ip:      a.ip.Ptr(),
+			Network: a.network.Ptr(),
+			Bond:    a.bond.Ptr(),
+			Vlan:    a.vlan.Ptr(),
+			Default: a.isDefault.Ptr(),
+		}.Build(),
+	}.Build()
+
+	_, err := a.Metal.UpdateHostInterface(a.Metal.Context(), req)
+
+	return err
+}
+
+// validate checks the MAC, VLAN and IP formats, that the interface's network
+// exists, and that the IP is within its subnet. Whichever of the IP and
+// network is not given is taken from the interface as it is.
+func (a *HostInterface) validate(name string) error {
+	ip, err := parseInterface(a.mac.Ptr(), a.ip.Ptr(), a.vlan.Ptr())
+	if err != nil {
+		return err
+	}
+
+	if !a.ip.IsSet() && !a.network.IsSet() {
+		return nil
+	}
+
+	nw := a.network.Val()
+
+	if !ip.IsValid() || nw == "" {
+		r := a.Metal.NewHostInterfaceReader(a.zone.Val(), a.cluster.Val(), a.host.Val(), name)
+
+		for resp, err := range r.Responses() {
+			if err != nil {
+				return err
+			}
+
+			if resp.GetName() != name { // the name is a glob
+				continue
+			}
+
+			if !ip.IsValid() {
+				ip, _ = netip.ParseAddr(resp.GetIp())
+			}
+
+			if nw == "" {
+				nw = resp.GetNetwork()
+			}
+		}
+	}
+
+	if nw == "" {
+		return nil
+	}
+
+	networks := make(map[string]string)
+
+	for resp, err := range a.Metal.NewNetworkReader(a.zone.Val(), nw).Responses() {
+		if err != nil {
+			return err
+		}
+
+		networks[resp.GetName()] = resp.GetSubnet()
+	}
+
+	return checkAddress(ip, a.zone.Val(), nw, networks)
+}
+
+// parseInterface checks the formats of the MAC, VLAN and IP being set, which
+// are nil if not, and returns the IP.
+func parseInterface(mac, ip *string, vlan *uint32) (netip.Addr, error) {
+	if mac != nil {
+		if _, err := net.ParseMAC(*mac); err != nil {
+			return netip.Addr{}, fmt.Errorf("%w: %w", errInvalidMAC, err)
+		}
+	}
+
+	if vlan != nil {
+		if err := checkVLAN(*vlan); err != nil {
+			return netip.Addr{}, err
+		}
+	}
+
+	if ip == nil {
+		return netip.Addr{}, nil
+	}
+
+	addr, err := netip.ParseAddr(*ip)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%w: %w", errInvalidIP, err)
+	}
+
+	return addr, nil
+}
+
+// checkAddress checks that the network nw is one of the zone's networks,
+// which map names to subnets, and that ip is within its subnet. An invalid ip
+// or a network with no subnet is not checked.
+func checkAddress(ip netip.Addr, zone, nw string, networks map[string]string) error {
+	subnet, ok := networks[nw]
+	if !ok {
+		return fmt.Errorf("%w %q in zone %q", errUnknownNetwork, nw, zone)
+	}
+
+	if !ip.IsValid() || subnet == "" {
+		return nil
+	}
+
+	p, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return fmt.Errorf("%w %q of %s: %w", errInvalidSubnet, subnet, nw, err)
+	}
+
+	if !p.Contains(ip) {
+		return fmt.Errorf("%w %s, not in %s %s", errInvalidIP, ip, nw, p)
+	}
+
+	return nil
+}
+
+// parent is where the host interfaces are in the schema.
+func (a *HostInterface) parent() diff.Path {
+	return path("zones", a.zone.Val(), "clusters", a.cluster.Val(), "hosts", a.host.Val())
+}
+
+func (a *HostInterface) remove(glob string) error {
+	names, err := matches(a.Metal.NewHostInterfaceReader(a.zone.Val(), a.cluster.Val(), a.host.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	if ok, err := a.confirmRemove(host+" "+iface, names); !ok || err != nil {
+		return err
+	}
+
+	done, err := a.journal(a.parent(), "interfaces", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteHostInterfacesRequest_builder{
+			Zone:    a.zone.Ptr(),
+			Cluster: a.cluster.Ptr(),
+			Host:    a.host.Ptr(),
+			Glob:    &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteHostInterfaces(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestParseInterface(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+	vlan := func(n uint32) *uint32 { return &n }
+
+	tests := []struct {
+		mac, ip *string
+		vlan    *uint32
+		err     error
+	}{
+		{},
+		{mac: ptr("00:11:22:33:44:55"), ip: ptr("10.0.0.1"), vlan: vlan(1)},
+		{mac: ptr("00:11:22:33:44"), err: errInvalidMAC},
+		{mac: ptr("not a mac"), err: errInvalidMAC},
+		{vlan: vlan(0), err: errInvalidVLAN},
+		{vlan: vlan(maxVLAN + 1), err: errInvalidVLAN},
+		{ip: ptr("10.0.0"), err: errInvalidIP},
+		{ip: ptr("10.0.0.1/24"), err: errInvalidIP},
+	}
+
+	for i, tt := range tests {
+		if _, err := parseInterface(tt.mac, tt.ip, tt.vlan); !errors.Is(err, tt.err) {
+			t.Errorf("%d: got %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	networks := map[string]string{
+		"n1": "10.0.0.0/24",
+		"n2": "",
+		"n3": "10.0.1.0",
+	}
+
+	tests := []struct {
+		ip  string
+		nw  string
+		err error
+	}{
+		{ip: "10.0.0.1", nw: "n1"},
+		{ip: "10.0.1.1", nw: "n1", err: errInvalidIP},
+		{nw: "n1"},                 // no address to check
+		{ip: "10.0.1.1", nw: "n2"}, // no subnet to check against
+		{ip: "10.0.1.1", nw: "n3", err: errInvalidSubnet},
+		{ip: "10.0.0.1", nw: "n4", err: errUnknownNetwork},
+		{ip: "10.0.0.1", nw: "n", err: errUnknownNetwork},
+	}
+
+	for _, tt := range tests {
+		var ip netip.Addr
+
+		if tt.ip != "" {
+			ip = netip.MustParseAddr(tt.ip)
+		}
+
+		if err := checkAddress(ip, "z1", tt.nw, networks); !errors.Is(err, tt.err) {
+			t.Errorf("%s %s: got %v, want %v", tt.ip, tt.nw, err, tt.err)
+		}
+	}
+}
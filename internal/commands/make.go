@@ -0,0 +1,391 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
+	"endobit.io/stack/internal/flags/set"
+)
+
+type Make struct {
+	*Root
+}
+
+func NewMake(r *Root) *Make {
+	return &Make{Root: r}
+}
+
+type MakeAttr struct {
+	*Make
+	make set.Make
+}
+
+func NewMakeAttr(a *Make) *MakeAttr {
+	return &MakeAttr{Make: a}
+}
+
+func (a *Make) Add() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   vendor + " name",
+		Short: "Add a " + vendor,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return a.create(args[0])
+		},
+	}
+
+	cmd.AddCommand(NewMakeAttr(a).Add())
+
+	return cmd
+}
+
+func (a *Make) Set() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   vendor + " name",
+		Short: "Set a " + vendor + "'s properties",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			done, err := a.journal(nil, "makes", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0]))
+		},
+	}
+
+	a.rename.Add(cmd.Flags(), vendor)
+
+	cmd.AddCommand(NewMakeAttr(a).Set())
+
+	return cmd
+}
+
+func (a *Make) List() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   vendor + " [glob]",
+		Short: "List one or more " + vendor + "s",
+		Long:  "List shows each " + vendor + " with the number of models and hosts that use it.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var glob string
+
+			if len(args) > 0 {
+				glob = args[0]
+			}
+			return a.list(glob)
+		},
+	}
+
+	a.output.Add(cmd.Flags())
+
+	cmd.AddCommand(NewMakeAttr(a).List())
+
+	return cmd
+}
+
+func (a *Make) Remove() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   vendor + " glob",
+		Short: "Remove one or more " + vendor + "s",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return a.remove(args[0])
+		},
+	}
+
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), vendor)
+
+	cmd.AddCommand(NewMakeAttr(a).Remove())
+
+	return cmd
+}
+
+func (a *Make) create(name string) error {
+	req := pb.CreateMakeRequest_builder{
+		Name: &name,
+	}.Build()
+
+	_, err := a.Metal.CreateMake(a.Metal.Context(), req)
+
+	return err
+}
+
+func (a *Make) list(glob string) error {
+	type row struct{ Make, Models, Hosts string }
+	t, err := a.newWriter(vendor, row{})
+	if err != nil {
+		return err
+	}
+
+	models, hosts, err := a.usage()
+	if err != nil {
+		return err
+	}
+
+	r := a.Metal.NewMakeReader(glob)
+
+	for resp, err := range r.Responses() {
+		if err != nil {
+			return err
+		}
+
+		_ = t.Write(row{
+			Make:   resp.GetName(),
+			Models: strconv.Itoa(models[resp.GetName()]),
+			Hosts:  strconv.Itoa(hosts[resp.GetName()]),
+		})
+	}
+
+	return t.Flush()
+}
+
+// usage counts the models and the hosts of each make.
+func (a *Make) usage() (map[string]int, map[string]int, error) {
+	models := make(map[string]int)
+	hosts := make(map[string]int)
+
+	for resp, err := range a.Metal.NewModelReader("", "").Responses() {
+		if err != nil {
+			return nil, nil, err
+		}
+
+		models[resp.GetMake()]++
+	}
+
+	for resp, err := range a.Metal.NewHostReader("", "", "").Responses() {
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if resp.GetMake() != "" {
+			hosts[resp.GetMake()]++
+		}
+	}
+
+	return models, hosts, nil
+}
+
+func (a *Make) update(name string) error {
+	req := pb.UpdateMakeRequest_builder{
+		Name: &name,
+		Fields: pb.UpdateMakeRequest_Fields_builder{
+			Name: a.rename.Ptr(),
+		}.Build(),
+	}.Build()
+
+	_, err := a.Metal.UpdateMake(a.Metal.Context(), req)
+
+	return err
+}
+
+func (a *Make) remove(glob string) error {
+	names, err := matches(a.Metal.NewMakeReader(glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	if ok, err := a.confirmRemove(vendor, names); !ok || err != nil {
+		return err
+	}
+
+	done, err := a.journal(nil, "makes", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteMakesRequest_builder{
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteMakes(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
+}
+
+func (a *MakeAttr) Add() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   attribute + " name value",
+		Short: "Add an " + attribute + " to a " + vendor,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := a.create(args[0]); err != nil {
+				return err
+			}
+
+			return a.update(args[0], args[1])
+		},
+	}
+
+	a.make.Add(cmd.Flags(), attribute, true)
+
+	return cmd
+}
+
+func (a *MakeAttr) Set() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   attribute + " name [value]",
+		Short: "Set a " + vendor + " " + attribute + "'s properties",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var value string
+
+			if len(args) > 1 {
+				value = args[1]
+			}
+
+			done, err := a.journal(a.parent(), "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
+		},
+	}
+
+	a.make.Add(cmd.Flags(), attribute, true)
+	a.rename.Add(cmd.Flags(), attribute)
+
+	return cmd
+}
+
+func (a *MakeAttr) List() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   attribute + " [glob]",
+		Short: "List one or more " + vendor + " " + attribute + "s",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var glob string
+
+			if len(args) > 0 {
+				glob = args[0]
+			}
+			return a.list(glob)
+		},
+	}
+
+	a.make.Add(cmd.Flags(), attribute, false)
+	a.output.Add(cmd.Flags())
+
+	return cmd
+}
+
+func (a *MakeAttr) Remove() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   attribute + " glob",
+		Short: "Remove one or more " + vendor + " " + attribute + "s",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return a.remove(args[0])
+		},
+	}
+
+	a.make.Add(cmd.Flags(), attribute, true)
+
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), vendor+" "+attribute)
+
+	return cmd
+}
+
+func (a *MakeAttr) create(attr string) error {
+	req := pb.CreateMakeAttrRequest_builder{
+		Make: a.make.Ptr(),
+		Name: &attr,
+	}.Build()
+
+	_, err := a.Metal.CreateMakeAttr(a.Metal.Context(), req)
+
+	return err
+}
+
+func (a *MakeAttr) list(glob string) error {
+	type row struct{ Make, Attr, Value string }
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
+
+	r := a.Metal.NewMakeAttrReader(a.make.Val(), glob)
+
+	for resp, err := range r.Responses() {
+		if err != nil {
+			return err
+		}
+
+		_ = t.Write(row{
+			Make:  resp.GetMake(),
+			Attr:  resp.GetName(),
+			Value: resp.GetValue(),
+		})
+	}
+
+	return t.Flush()
+}
+
+func (a *MakeAttr) update(attr, val string) error {
+	var value *string
+
+	if val != "" {
+		value = &val
+	}
+
+	req := pb.UpdateMakeAttrRequest_builder{
+		Make: a.make.Ptr(),
+		Name: &attr,
+		Fields: pb.UpdateMakeAttrRequest_Fields_builder{
+			Name:  a.rename.Ptr(),
+			Value: value,
+		}.Build(),
+	}.Build()
+
+	_, err := a.Metal.UpdateMakeAttr(a.Metal.Context(), req)
+
+	return err
+}
+
+// parent is where the make attrs are in the schema.
+func (a *MakeAttr) parent() diff.Path {
+	return path("makes", a.make.Val())
+}
+
+func (a *MakeAttr) remove(glob string) error {
+	names, err := matches(a.Metal.NewMakeAttrReader(a.make.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	if ok, err := a.confirmRemove(vendor+" "+attribute, names); !ok || err != nil {
+		return err
+	}
+
+	done, err := a.journal(a.parent(), "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteMakeAttrsRequest_builder{
+			Make: a.make.Ptr(),
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteMakeAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
+}
@@ -5,8 +5,8 @@ import (
 
 	"endobit.io/metal"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
 	"endobit.io/stack/internal/flags/set"
-	"endobit.io/table"
 )
 
 type Model struct {
@@ -55,7 +55,12 @@ func (m *Model) Set() *cobra.Command {
 		Short: "Set a " + model + "'s properties",
 		Args:  cobra.ExactArgs(2),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return m.update(args[0], args[1])
+			done, err := m.journal(path("makes", args[0]), "models", args[1])
+			if err != nil {
+				return err
+			}
+
+			return done(m.update(args[0], args[1]))
 		},
 	}
 
@@ -87,6 +92,8 @@ func (m *Model) List() *cobra.Command {
 		},
 	}
 
+	m.output.Add(cmd.Flags())
+
 	cmd.AddCommand(NewModelAttr(m).List())
 
 	return cmd
@@ -102,6 +109,10 @@ func (m *Model) Remove() *cobra.Command {
 		},
 	}
 
+	m.dryRun.Add(cmd.Flags())
+	m.yes.Add(cmd.Flags())
+	m.force.Add(cmd.Flags(), model)
+
 	cmd.AddCommand(NewModelAttr(m).Remove())
 
 	return cmd
@@ -119,8 +130,10 @@ func (m *Model) create(vendor, model string) error {
 
 func (m *Model) list(vendor, glob string) error {
 	type row struct{ Make, Model, Arch string }
-	t := table.New()
-	defer t.Flush()
+	t, err := m.newWriter(model, row{})
+	if err != nil {
+		return err
+	}
 
 	r := m.Metal.NewModelReader(vendor, glob)
 
@@ -137,7 +150,7 @@ func (m *Model) list(vendor, glob string) error {
 
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (m *Model) update(vendor, model string) error {
@@ -163,13 +176,33 @@ func (m *Model) update(vendor, model string) error {
 }
 
 func (m *Model) remove(vendor, glob string) error {
-	req := pb.DeleteModelsRequest_builder{
-		Make: &vendor,
-		Glob: &glob,
-	}.Build()
+	names, err := matches(m.Metal.NewModelReader(vendor, glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := m.Metal.DeleteModels(m.Metal.Context(), req)
-	return err
+	if ok, err := m.confirmRemove(model, names); !ok || err != nil {
+		return err
+	}
+
+	done, err := m.journal(path("makes", vendor), "models", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteModelsRequest_builder{
+			Make: &vendor,
+			Glob: &glob,
+		}.Build()
+
+		if _, err := m.Metal.DeleteModels(m.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
 
 func (a *ModelAttr) Add() *cobra.Command {
@@ -204,7 +237,12 @@ func (a *ModelAttr) Set() *cobra.Command {
 				value = args[1]
 			}
 
-			return a.update(args[0], value)
+			done, err := a.journal(a.parent(), "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
 		},
 	}
 
@@ -232,6 +270,7 @@ func (a *ModelAttr) List() *cobra.Command {
 
 	a.make.Add(cmd.Flags(), model, true)
 	a.model.Add(cmd.Flags(), attribute, true)
+	a.output.Add(cmd.Flags())
 
 	return cmd
 }
@@ -249,6 +288,10 @@ func (a *ModelAttr) Remove() *cobra.Command {
 	a.make.Add(cmd.Flags(), model, true)
 	a.model.Add(cmd.Flags(), attribute, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), model+" "+attribute)
+
 	return cmd
 }
 
@@ -265,8 +308,10 @@ func (a *ModelAttr) create(attr string) error {
 
 func (a *ModelAttr) list(glob string) error {
 	type row struct{ Make, Model, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewModelAttrReader(a.model.Val(), glob)
 
@@ -283,7 +328,7 @@ func (a *ModelAttr) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *ModelAttr) update(attr, val string) error {
@@ -307,13 +352,37 @@ func (a *ModelAttr) update(attr, val string) error {
 	return err
 }
 
+// parent is where the model attrs are in the schema.
+func (a *ModelAttr) parent() diff.Path {
+	return path("makes", a.make.Val(), "models", a.model.Val())
+}
+
 func (a *ModelAttr) remove(glob string) error {
-	req := pb.DeleteModelAttrsRequest_builder{
-		Model: a.model.Ptr(),
-		Glob:  &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewModelAttrReader(a.model.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteModelAttrs(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(model+" "+attribute, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteModelAttrsRequest_builder{
+			Model: a.model.Ptr(),
+			Glob:  &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteModelAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
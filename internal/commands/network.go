@@ -0,0 +1,553 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
+	"endobit.io/stack/internal/flags/set"
+)
+
+type Network struct {
+	*Root
+	subnet  set.Subnet
+	gateway set.Gateway
+	vlan    set.VLAN
+	mtu     set.MTU
+}
+
+// maxVLAN is the highest usable 802.1Q VLAN ID.
+const maxVLAN = 4094
+
+// subnet is a network's prefix, for finding overlaps.
+type subnet struct {
+	zone, name string
+	prefix     netip.Prefix
+}
+
+func NewNetwork(r *Root) *Network {
+	return &Network{Root: r}
+}
+
+type NetworkAttr struct {
+	*Network
+	network set.Network
+}
+
+func NewNetworkAttr(a *Network) *NetworkAttr {
+	return &NetworkAttr{Network: a}
+}
+
+func (a *Network) Add() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   network + " name",
+		Short: "Add a " + network + " to a zone",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a.vlan.AcceptZero(cmd.Flags())
+
+			if err := a.validate(args[0]); err != nil {
+				return err
+			}
+
+			if err := a.create(args[0]); err != nil {
+				return err
+			}
+
+			return a.update(args[0])
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), network, true)
+	a.subnet.Add(cmd.Flags(), network)
+	a.gateway.Add(cmd.Flags(), network)
+	a.vlan.Add(cmd.Flags(), network)
+	a.mtu.Add(cmd.Flags(), network)
+
+	cmd.AddCommand(NewNetworkAttr(a).Add())
+
+	return cmd
+}
+
+func (a *Network) Set() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   network + " name",
+		Short: "Set a " + network + "'s properties",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a.vlan.AcceptZero(cmd.Flags())
+
+			if err := a.validate(args[0]); err != nil {
+				return err
+			}
+
+			done, err := a.journal(a.parent(), "networks", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0]))
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), network, true)
+	a.rename.Add(cmd.Flags(), network)
+	a.subnet.Add(cmd.Flags(), network)
+	a.gateway.Add(cmd.Flags(), network)
+	a.vlan.Add(cmd.Flags(), network)
+	a.mtu.Add(cmd.Flags(), network)
+
+	cmd.AddCommand(NewNetworkAttr(a).Set())
+
+	return cmd
+}
+
+func (a *Network) List() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   network + " [glob]",
+		Short: "List one or more " + network + "s",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var glob string
+
+			if len(args) > 0 {
+				glob = args[0]
+			}
+			return a.list(glob)
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), network, false)
+	a.output.Add(cmd.Flags())
+
+	cmd.AddCommand(NewNetworkAttr(a).List())
+
+	return cmd
+}
+
+func (a *Network) Remove() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   network + " glob",
+		Short: "Remove one or more " + network + "s",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return a.remove(args[0])
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), network, true)
+
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), network)
+
+	cmd.AddCommand(NewNetworkAttr(a).Remove())
+
+	return cmd
+}
+
+func (a *Network) create(network string) error {
+	req := pb.CreateNetworkRequest_builder{
+		Zone: a.zone.Ptr(),
+		Name: &network,
+	}.Build()
+
+	_, err := a.Metal.CreateNetwork(a.Metal.Context(), req)
+
+	return err
+}
+
+// list shows the networks and then reports any that overlap as an error.
+func (a *Network) list(glob string) error {
+	type row struct {
+		Zone    string
+		Network string
+		Subnet  string
+		Gateway string `table:",omitempty"`
+		VLAN    string `table:",omitempty"`
+		MTU     string `table:",omitempty"`
+	}
+
+	t, err := a.newWriter(network, row{})
+	if err != nil {
+		return err
+	}
+
+	var subnets []subnet
+
+	r := a.Metal.NewNetworkReader(a.zone.Val(), glob)
+
+	for resp, err := range r.Responses() {
+		if err != nil {
+			return err
+		}
+
+		var vlan, mtu string
+
+		if resp.HasVlan() {
+			vlan = strconv.Itoa(int(resp.GetVlan()))
+		}
+		if resp.HasMtu() {
+			mtu = strconv.Itoa(int(resp.GetMtu()))
+		}
+
+		if p, err := netip.ParsePrefix(resp.GetSubnet()); err == nil {
+			subnets = append(subnets, subnet{zone: resp.GetZone(), name: resp.GetName(), prefix: p})
+		}
+
+		_ = t.Write(row{
+			Zone:    resp.GetZone(),
+			Network: resp.GetName(),
+			Subnet:  resp.GetSubnet(),
+			Gateway: resp.GetGateway(),
+			VLAN:    vlan,
+			MTU:     mtu,
+		})
+	}
+
+	if err := t.Flush(); err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for i, s := range subnets {
+		for _, o := range subnets[i+1:] {
+			if s.zone == o.zone && s.prefix.Overlaps(o.prefix) {
+				errs = append(errs, fmt.Errorf("%w: %s %s and %s %s in zone %s",
+					errOverlap, s.name, s.prefix, o.name, o.prefix, s.zone))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks the subnet, gateway and VLAN flags before they are sent,
+// reading the zone's networks for checkNetwork.
+func (a *Network) validate(name string) error {
+	if a.vlan.IsSet() {
+		if err := checkVLAN(a.vlan.Val()); err != nil {
+			return err
+		}
+	}
+
+	if !a.subnet.IsSet() && !a.gateway.IsSet() {
+		return nil
+	}
+
+	var networks []subnet
+
+	for resp, err := range a.Metal.NewNetworkReader(a.zone.Val(), "").Responses() {
+		if err != nil {
+			return err
+		}
+
+		if p, err := netip.ParsePrefix(resp.GetSubnet()); err == nil {
+			networks = append(networks, subnet{zone: resp.GetZone(), name: resp.GetName(), prefix: p})
+		}
+	}
+
+	return checkNetwork(name, a.subnet.Ptr(), a.gateway.Ptr(), networks)
+}
+
+// checkVLAN checks that vlan is a usable 802.1Q VLAN ID.
+func checkVLAN(vlan uint32) error {
+	if vlan < 1 || vlan > maxVLAN {
+		return fmt.Errorf("%w %d, must be 1-%d", errInvalidVLAN, vlan, maxVLAN)
+	}
+
+	return nil
+}
+
+// checkNetwork checks the subnet and gateway being set on the network name
+// against the networks of its zone, which include the network itself unless
+// it is new. The subnet must be masked and overlap no other network, and the
+// gateway must be within the subnet, or within the current one if the subnet
+// is not being set. Nil is not being set.
+func checkNetwork(name string, cidr, gateway *string, networks []subnet) error {
+	var prefix netip.Prefix
+
+	if cidr != nil {
+		p, err := netip.ParsePrefix(*cidr)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errInvalidSubnet, err)
+		}
+
+		if p != p.Masked() {
+			return fmt.Errorf("%w %s, did you mean %s", errInvalidSubnet, p, p.Masked())
+		}
+
+		prefix = p
+	}
+
+	for _, n := range networks {
+		if n.name == name {
+			if !prefix.IsValid() {
+				prefix = n.prefix // the gateway is checked against the current subnet
+			}
+
+			continue
+		}
+
+		if cidr != nil && prefix.Overlaps(n.prefix) {
+			return fmt.Errorf("%w: %s overlaps %s %s", errOverlap, prefix, n.name, n.prefix)
+		}
+	}
+
+	if gateway != nil {
+		gw, err := netip.ParseAddr(*gateway)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errInvalidGateway, err)
+		}
+
+		if prefix.IsValid() && !prefix.Contains(gw) {
+			return fmt.Errorf("%w %s, not in %s", errInvalidGateway, gw, prefix)
+		}
+	}
+
+	return nil
+}
+
+func (a *Network) update(network string) error {
+	req := pb.UpdateNetworkRequest_builder{
+		Zone: a.zone.Ptr(),
+		Name: &network,
+		Fields: pb.UpdateNetworkRequest_Fields_builder{
+			Name:    a.rename.Ptr(),
+			Subnet:  a.subnet.Ptr(),
+			Gateway: a.gateway.Ptr(),
+			Vlan:    a.vlan.Ptr(),
+			Mtu:     a.mtu.Ptr(),
+		}.Build(),
+	}.Build()
+
+	_, err := a.Metal.UpdateNetwork(a.Metal.Context(), req)
+
+	return err
+}
+
+// parent is where the networks are in the schema.
+func (a *Network) parent() diff.Path {
+	return path("zones", a.zone.Val())
+}
+
+func (a *Network) remove(glob string) error {
+	names, err := matches(a.Metal.NewNetworkReader(a.zone.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	if ok, err := a.confirmRemove(network, names); !ok || err != nil {
+		return err
+	}
+
+	done, err := a.journal(a.parent(), "networks", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteNetworksRequest_builder{
+			Zone: a.zone.Ptr(),
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteNetworks(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
+}
+
+func (a *NetworkAttr) Add() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   attribute + " name value",
+		Short: "Add an " + attribute + " to a " + network,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := a.create(args[0]); err != nil {
+				return err
+			}
+
+			return a.update(args[0], args[1])
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), network, true)
+	a.network.Add(cmd.Flags(), attribute, true)
+
+	return cmd
+}
+
+func (a *NetworkAttr) Set() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   attribute + " name [value]",
+		Short: "Set a " + network + " " + attribute + "'s properties",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var value string
+
+			if len(args) > 1 {
+				value = args[1]
+			}
+
+			done, err := a.journal(a.parent(), "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), network, true)
+	a.network.Add(cmd.Flags(), attribute, true)
+	a.rename.Add(cmd.Flags(), network)
+
+	return cmd
+}
+
+func (a *NetworkAttr) List() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   attribute + " [glob]",
+		Short: "List one or more " + network + " " + attribute + "s",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var glob string
+
+			if len(args) > 0 {
+				glob = args[0]
+			}
+			return a.list(glob)
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), network, false)
+	a.network.Add(cmd.Flags(), attribute, false)
+	a.output.Add(cmd.Flags())
+
+	return cmd
+}
+
+func (a *NetworkAttr) Remove() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   attribute + " glob",
+		Short: "Remove one or more " + network + " " + attribute + "s",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return a.remove(args[0])
+		},
+	}
+
+	a.zone.Add(cmd.Flags(), network, true)
+	a.network.Add(cmd.Flags(), attribute, true)
+
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), network+" "+attribute)
+
+	return cmd
+}
+
+func (a *NetworkAttr) create(attr string) error {
+	req := pb.CreateNetworkAttrRequest_builder{
+		Zone:    a.zone.Ptr(),
+		Network: a.network.Ptr(),
+		Name:    &attr,
+	}.Build()
+
+	_, err := a.Metal.CreateNetworkAttr(a.Metal.Context(), req)
+
+	return err
+}
+
+func (a *NetworkAttr) list(glob string) error {
+	type row struct{ Zone, Network, Attr, Value string }
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
+
+	r := a.Metal.NewNetworkAttrReader(a.zone.Val(), a.network.Val(), glob)
+
+	for resp, err := range r.Responses() {
+		if err != nil {
+			return err
+		}
+
+		_ = t.Write(row{
+			Zone:    resp.GetZone(),
+			Network: resp.GetNetwork(),
+			Attr:    resp.GetName(),
+			Value:   resp.GetValue(),
+		})
+	}
+
+	return t.Flush()
+}
+
+func (a *NetworkAttr) update(attr, val string) error {
+	var value *string
+
+	if val != "" {
+		value = &val
+	}
+
+	req := pb.UpdateNetworkAttrRequest_builder{
+		Zone:    a.zone.Ptr(),
+		Network: a.network.Ptr(),
+		Name:    &attr,
+		Fields: pb.UpdateNetworkAttrRequest_Fields_builder{
+			Name:  a.rename.Ptr(),
+			Value: value,
+		}.Build(),
+	}.Build()
+
+	_, err := a.Metal.UpdateNetworkAttr(a.Metal.Context(), req)
+
+	return err
+}
+
+// parent is where the network attrs are in the schema.
+func (a *NetworkAttr) parent() diff.Path {
+	return path("zones", a.zone.Val(), "networks", a.network.Val())
+}
+
+func (a *NetworkAttr) remove(glob string) error {
+	names, err := matches(a.Metal.NewNetworkAttrReader(a.zone.Val(), a.network.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	if ok, err := a.confirmRemove(network+" "+attribute, names); !ok || err != nil {
+		return err
+	}
+
+	done, err := a.journal(a.parent(), "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteNetworkAttrsRequest_builder{
+			Zone:    a.zone.Ptr(),
+			Network: a.network.Ptr(),
+			Glob:    &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteNetworkAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestCheckVLAN(t *testing.T) {
+	tests := []struct {
+		vlan uint32
+		err  error
+	}{
+		{vlan: 0, err: errInvalidVLAN},
+		{vlan: 1},
+		{vlan: maxVLAN},
+		{vlan: maxVLAN + 1, err: errInvalidVLAN},
+	}
+
+	for _, tt := range tests {
+		if err := checkVLAN(tt.vlan); !errors.Is(err, tt.err) {
+			t.Errorf("%d: got %v, want %v", tt.vlan, err, tt.err)
+		}
+	}
+}
+
+func TestCheckNetwork(t *testing.T) {
+	networks := []subnet{
+		{zone: "z1", name: "n1", prefix: netip.MustParsePrefix("10.0.0.0/24")},
+		{zone: "z1", name: "n2", prefix: netip.MustParsePrefix("10.1.0.0/16")},
+	}
+
+	tests := []struct {
+		name    string
+		subnet  string
+		gateway string
+		err     error
+	}{
+		{name: "n3", subnet: "10.2.0.0/24", gateway: "10.2.0.1"},
+		{name: "n3", subnet: "10.2.0.1/24", err: errInvalidSubnet},
+		{name: "n3", subnet: "10.2.0.0", err: errInvalidSubnet},
+		{name: "n3", subnet: "10.1.2.0/24", err: errOverlap},
+		{name: "n3", subnet: "10.0.0.0/8", err: errOverlap},
+		{name: "n1", subnet: "10.0.0.0/23"}, // set, or renamed, n1 overlaps only itself
+		{name: "n3", subnet: "10.2.0.0/24", gateway: "10.3.0.1", err: errInvalidGateway},
+		{name: "n3", subnet: "10.2.0.0/24", gateway: "10.2.0", err: errInvalidGateway},
+		{name: "n1", gateway: "10.0.0.1"},
+		{name: "n1", gateway: "10.1.0.1", err: errInvalidGateway}, // not in the current subnet
+		{name: "n3", gateway: "10.9.0.1"},                         // no subnet to check against
+	}
+
+	for _, tt := range tests {
+		var cidr, gateway *string
+
+		if tt.subnet != "" {
+			cidr = &tt.subnet
+		}
+
+		if tt.gateway != "" {
+			gateway = &tt.gateway
+		}
+
+		if err := checkNetwork(tt.name, cidr, gateway, networks); !errors.Is(err, tt.err) {
+			t.Errorf("%s %q %q: got %v, want %v", tt.name, tt.subnet, tt.gateway, err, tt.err)
+		}
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"github.com/spf13/cobra"
 
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
 	"endobit.io/stack/internal/flags/set"
-	"endobit.io/table"
 )
 
 type Rack struct {
@@ -52,7 +52,12 @@ func (a *Rack) Set() *cobra.Command {
 		Short: "Set a " + rack + "'s properties",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return a.update(args[0])
+			done, err := a.journal(a.parent(), "racks", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0]))
 		},
 	}
 
@@ -80,6 +85,7 @@ func (a *Rack) List() *cobra.Command {
 	}
 
 	a.zone.Add(cmd.Flags(), rack, false)
+	a.output.Add(cmd.Flags())
 
 	cmd.AddCommand(NewRackAttr(a).List())
 
@@ -98,6 +104,10 @@ func (a *Rack) Remove() *cobra.Command {
 
 	a.zone.Add(cmd.Flags(), rack, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), rack)
+
 	cmd.AddCommand(NewRackAttr(a).Remove())
 
 	return cmd
@@ -116,8 +126,10 @@ func (a *Rack) create(rack string) error {
 
 func (a *Rack) list(glob string) error {
 	type row struct{ Zone, Rack string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(rack, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewRackReader(a.zone.Val(), glob)
 
@@ -132,7 +144,7 @@ func (a *Rack) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *Rack) update(rack string) error {
@@ -149,15 +161,39 @@ func (a *Rack) update(rack string) error {
 	return err
 }
 
+// parent is where the racks are in the schema.
+func (a *Rack) parent() diff.Path {
+	return path("zones", a.zone.Val())
+}
+
 func (a *Rack) remove(glob string) error {
-	req := pb.DeleteRacksRequest_builder{
-		Zone: a.zone.Ptr(),
-		Glob: &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewRackReader(a.zone.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteRacks(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(rack, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "racks", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteRacksRequest_builder{
+			Zone: a.zone.Ptr(),
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteRacks(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
 
 func (a *RackAttr) Add() *cobra.Command {
@@ -192,7 +228,12 @@ func (a *RackAttr) Set() *cobra.Command {
 				value = args[1]
 			}
 
-			return a.update(args[0], value)
+			done, err := a.journal(a.parent(), "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
 		},
 	}
 
@@ -220,6 +261,7 @@ func (a *RackAttr) List() *cobra.Command {
 
 	a.zone.Add(cmd.Flags(), rack, false)
 	a.rack.Add(cmd.Flags(), attribute, false)
+	a.output.Add(cmd.Flags())
 
 	return cmd
 }
@@ -237,6 +279,10 @@ func (a *RackAttr) Remove() *cobra.Command {
 	a.zone.Add(cmd.Flags(), rack, true)
 	a.rack.Add(cmd.Flags(), attribute, true)
 
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), rack+" "+attribute)
+
 	return cmd
 }
 
@@ -254,8 +300,10 @@ func (a *RackAttr) create(attr string) error {
 
 func (a *RackAttr) list(glob string) error {
 	type row struct{ Zone, Rack, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewRackAttrReader(a.zone.Val(), a.rack.Val(), glob)
 
@@ -272,7 +320,7 @@ func (a *RackAttr) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *RackAttr) update(attr, val string) error {
@@ -297,14 +345,38 @@ func (a *RackAttr) update(attr, val string) error {
 	return err
 }
 
+// parent is where the rack attrs are in the schema.
+func (a *RackAttr) parent() diff.Path {
+	return path("zones", a.zone.Val(), "racks", a.rack.Val())
+}
+
 func (a *RackAttr) remove(glob string) error {
-	req := pb.DeleteRackAttrsRequest_builder{
-		Zone: a.zone.Ptr(),
-		Rack: a.rack.Ptr(),
-		Glob: &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewRackAttrReader(a.zone.Val(), a.rack.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteRackAttrs(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(rack+" "+attribute, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteRackAttrsRequest_builder{
+			Zone: a.zone.Ptr(),
+			Rack: a.rack.Ptr(),
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteRackAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
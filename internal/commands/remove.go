@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"iter"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// defaultRemoveLimit is how many objects a remove may match without --force
+// when the context does not set a limit.
+const defaultRemoveLimit = 10
+
+// matches collects the names of the objects a reader returns.
+func matches[T interface{ GetName() string }](responses iter.Seq2[T, error]) ([]string, error) {
+	var names []string
+
+	for resp, err := range responses {
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, resp.GetName())
+	}
+
+	return names, nil
+}
+
+// confirmRemove shows the objects a remove matched and reports whether to
+// delete them. Nothing is deleted on a dry run, when nothing matched, or when
+// the user says no. Matching more than the limit is an error unless --force
+// is given, and without --yes the user is asked on the terminal. The objects
+// are then deleted by name, so each name must match only itself.
+func (r *Root) confirmRemove(object string, names []string) (bool, error) {
+	if len(names) == 0 {
+		fmt.Fprintf(os.Stderr, "no %ss match\n", object)
+		return false, nil
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+
+	if r.dryRun.Val() {
+		return false, nil
+	}
+
+	limit := defaultRemoveLimit
+	if r.Profile != nil && r.Profile.RemoveLimit > 0 {
+		limit = r.Profile.RemoveLimit
+	}
+
+	if len(names) > limit && !r.force.Val() {
+		return false, fmt.Errorf("%w: %d %ss match, the limit is %d", errTooMany, len(names), object, limit)
+	}
+
+	return r.confirm(fmt.Sprintf("Remove %d %ss?", len(names), object))
+}
+
+// confirm reports whether to go ahead, which is yes with --yes and otherwise
+// asks the question on the terminal.
+func (r *Root) confirm(question string) (bool, error) {
+	if r.yes.Val() {
+		return true, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) { //nolint:gosec
+		return false, errNotConfirmed
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// literal returns the glob that matches only the object named name. Each
+// glob character is put in a bracket expression of its own, which matches
+// just that character; the backslash is doubled as it escapes in some globs.
+func literal(name string) string {
+	var b strings.Builder
+
+	for _, r := range name {
+		switch r {
+		case '*', '?', '[':
+			b.WriteString("[" + string(r) + "]")
+		case '\\':
+			b.WriteString(`[\\]`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
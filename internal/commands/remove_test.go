@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"path"
+	"testing"
+)
+
+func TestLiteral(t *testing.T) {
+	names := []string{"h1", "h*", "h?", "h[1]", `h\1`, "*", "h1]"}
+
+	for _, name := range names {
+		glob := literal(name)
+
+		for _, other := range append(names, "h", "h11", "h[1") {
+			ok, err := path.Match(glob, other)
+			if err != nil {
+				t.Fatalf("%q: %v", glob, err)
+			}
+
+			if ok != (other == name) {
+				t.Errorf("%q matching %q: got %t, want %t", glob, other, ok, other == name)
+			}
+		}
+	}
+}
@@ -3,30 +3,47 @@ package commands
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"os"
-	"path/filepath"
+	"slices"
 
-	"github.com/goccy/go-yaml"
 	"github.com/spf13/cobra"
-	"google.golang.org/protobuf/encoding/protojson"
+	"golang.org/x/term"
 
 	"endobit.io/metal"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
 	"endobit.io/mops"
+	"endobit.io/stack/internal/config"
+	"endobit.io/stack/internal/diff"
+	"endobit.io/stack/internal/flags"
 	"endobit.io/stack/internal/flags/set"
+	"endobit.io/stack/internal/output"
+	"endobit.io/stack/internal/schema"
+	"endobit.io/stack/internal/session"
 )
 
 type Root struct {
-	Metal   *metal.Client
-	Ops     *mops.Client
-	zone    set.Zone
-	cluster set.Cluster
-	host    set.Host
-	json    set.JSON
-	rename  set.Rename
+	Metal     *metal.Client
+	Ops       *mops.Client
+	Config    *config.Config
+	Session   *session.Session
+	Profile   *config.Context
+	zone      set.Zone
+	cluster   set.Cluster
+	host      set.Host
+	json      set.JSON
+	rename    set.Rename
+	dryRun    set.DryRun
+	yes       set.Yes
+	force     set.Force
+	prune     set.Prune
+	list      set.List
+	live      set.Live
+	format    set.Format
+	merge     set.Merge
+	split     set.Split
+	passStdin set.PassStdin
+	output    set.Output
 }
 
 func (r *Root) New(verb Verb) *cobra.Command {
@@ -38,7 +55,9 @@ func (r *Root) New(verb Verb) *cobra.Command {
 	cluster := NewCluster(r)
 	host := NewHost(r)
 	rack := NewRack(r)
+	vendor := NewMake(r)
 	model := NewModel(r)
+	network := NewNetwork(r)
 	zone := NewZone(r)
 
 	switch verb {
@@ -55,25 +74,103 @@ func (r *Root) New(verb Verb) *cobra.Command {
 			cluster.Add(),
 			environment.Add(),
 			host.Add(),
+			vendor.Add(),
 			model.Add(),
+			network.Add(),
 			rack.Add(),
 			zone.Add())
 
+	case Apply:
+		cmd = cobra.Command{
+			Use:   "apply file...",
+			Short: "Make the live schema match a file",
+			Long: "Apply creates and updates objects so the live schema matches the file. " +
+				"Objects missing from the file are left alone unless --prune is given, which needs --zone.",
+			Args: cobra.MinimumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return r.apply(args)
+			},
+		}
+
+		r.format.Add(cmd.Flags())
+		r.dryRun.Add(cmd.Flags())
+		r.prune.Add(cmd.Flags(), "objects")
+		r.json.Add(cmd.Flags(), "changes")
+		r.zone.Add(cmd.Flags(), "schema", false)
+		r.cluster.Add(cmd.Flags(), "schema", false)
+
+	case Config:
+		cmd = cobra.Command{
+			Use:   "config",
+			Short: "Modify the client configuration",
+		}
+
+		ctx := NewContext(r)
+
+		cmd.AddCommand(
+			ctx.List(),
+			ctx.Set(),
+			ctx.Use())
+
+	case Diff:
+		cmd = cobra.Command{
+			Use:          "diff file...",
+			Short:        "Compare a schema file with the live schema",
+			Long:         "Diff shows how loading the file would change the live schema, and exits non-zero if it would.",
+			Args:         cobra.MinimumNArgs(1),
+			SilenceUsage: true,
+			RunE: func(_ *cobra.Command, args []string) error {
+				return r.diff(args)
+			},
+		}
+
+		r.format.Add(cmd.Flags())
+		r.json.Add(cmd.Flags(), "changes")
+		r.zone.Add(cmd.Flags(), "schema", false)
+		r.cluster.Add(cmd.Flags(), "schema", false)
+		r.host.Add(cmd.Flags(), "schema", false)
+
 	case Dump:
 		cmd = cobra.Command{
 			Use:   "dump",
 			Short: "Dump stack schema",
-			Args:  cobra.NoArgs,
+			Long: "Dump writes the schema with named objects sorted, so dumps of the same schema are identical. " +
+				"With --merge it rewrites an existing YAML file, keeping the file's comments. " +
+				"With --split it writes a directory with a file per zone and cluster, which load reads back.",
+			Args: cobra.NoArgs,
 			RunE: func(_ *cobra.Command, _ []string) error {
 				return r.dump()
 			},
 		}
 
 		r.json.Add(cmd.Flags(), "schema")
+		r.merge.Add(cmd.Flags(), "schema")
+		r.split.Add(cmd.Flags(), "schema")
+		cmd.MarkFlagsMutuallyExclusive(flags.Merge, flags.Split)
 		r.zone.Add(cmd.Flags(), "schema", false)
 		r.cluster.Add(cmd.Flags(), "schema", false)
 		r.host.Add(cmd.Flags(), "schema", false)
 
+	case Schema:
+		cmd = cobra.Command{
+			Use:   "schema",
+			Short: "Describe the schema document",
+		}
+
+		cmd.AddCommand(&cobra.Command{
+			Use:   "jsonschema",
+			Short: "Print a JSON Schema for the dump and load document",
+			Long: "Prints a JSON Schema generated from the schema protobuf, for editors such as " +
+				"yaml-language-server and for pre-commit checks.",
+			Args: cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+
+				return enc.Encode(schema.JSONSchema())
+			},
+		})
+
 	case Set:
 		cmd = cobra.Command{
 			Use:     "set",
@@ -87,10 +184,48 @@ func (r *Root) New(verb Verb) *cobra.Command {
 			cluster.Set(),
 			environment.Set(),
 			host.Set(),
+			vendor.Set(),
 			model.Set(),
+			network.Set(),
 			rack.Set(),
 			zone.Set())
 
+	case Snapshot:
+		cmd = cobra.Command{
+			Use:   "snapshot",
+			Short: "Save and restore local copies of the schema",
+			Long: "Snapshots are kept in $XDG_DATA_HOME/stack/snapshots. They record the server, " +
+				"user and time they were taken.",
+		}
+
+		snap := NewSnapshots(r)
+
+		cmd.AddCommand(
+			snap.Diff(),
+			snap.List(),
+			snap.Restore(),
+			snap.Save())
+
+	case Undo:
+		cmd = cobra.Command{
+			Use:   "undo [id]",
+			Short: "Undo the last set, unset or remove",
+			Long: "Set, unset and remove save the objects they change to a local journal first. Undo puts " +
+				"back the objects saved by the newest entry for the server, or by the entry given, and " +
+				"drops the entry. Removed objects are created again with their attrs and renamed objects " +
+				"get their old names back, and fields the command set are unset again. An entry of another server needs --force.",
+			Args: cobra.MaximumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return r.undo(args)
+			},
+		}
+
+		r.list.Add(cmd.Flags(), "journal")
+		r.dryRun.Add(cmd.Flags())
+		r.force.AddServer(cmd.Flags(), "journal entry")
+		r.json.Add(cmd.Flags(), "changes")
+		r.output.Add(cmd.Flags())
+
 	case Unset:
 		cmd = cobra.Command{
 			Use:   "unset",
@@ -115,18 +250,59 @@ func (r *Root) New(verb Verb) *cobra.Command {
 			cluster.List(),
 			environment.List(),
 			host.List(),
+			vendor.List(),
 			model.List(),
+			network.List(),
 			rack.List(),
 			zone.List())
 
 	case Load:
 		cmd = cobra.Command{
-			Use:     "load filename",
+			Use:     "load file...",
 			Aliases: []string{"ld"},
-			Args:    cobra.ExactArgs(1),
+			Args:    cobra.MinimumNArgs(1),
 			Short:   "Load objects",
-			RunE: func(_ *cobra.Command, args []string) error {
-				return r.load(args[0])
+			Long: "Load creates the objects in the files. Directories are searched for .json, .yaml " +
+				"and .yml files, and - reads from stdin. Everything is merged into one schema before loading.",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if r.dryRun.Val() {
+					cmd.SilenceUsage = true
+
+					return r.diff(args)
+				}
+
+				return r.load(args)
+			},
+		}
+
+		r.format.Add(cmd.Flags())
+		r.dryRun.Add(cmd.Flags())
+		r.json.Add(cmd.Flags(), "changes")
+		r.zone.Add(cmd.Flags(), "schema", false)
+		r.cluster.Add(cmd.Flags(), "schema", false)
+		r.host.Add(cmd.Flags(), "schema", false)
+
+	case Login:
+		cmd = cobra.Command{
+			Use:   "login",
+			Short: "Log in to the metal server",
+			Long:  "Authorizes with the metal server and caches the session token for later commands.",
+			Args:  cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				return r.login()
+			},
+		}
+
+		r.passStdin.Add(cmd.Flags())
+
+	case Logout:
+		cmd = cobra.Command{
+			Use:   "logout",
+			Short: "Log out of the metal server",
+			Long:  "Removes the cached session token for the metal server.",
+			Args:  cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				return r.Session.Logout()
 			},
 		}
 
@@ -150,6 +326,8 @@ func (r *Root) New(verb Verb) *cobra.Command {
 			Use:     "remove",
 			Aliases: []string{"del", "rm"},
 			Short:   "Remove objects",
+			Long: "Remove lists the objects the glob matches and asks before removing them, unless --yes is given. " +
+				"Matching more than the context's remove_limit, 10 by default, needs --force.",
 		}
 
 		cmd.AddCommand(
@@ -158,104 +336,221 @@ func (r *Root) New(verb Verb) *cobra.Command {
 			cluster.Remove(),
 			environment.Remove(),
 			host.Remove(),
+			vendor.Remove(),
 			model.Remove(),
+			network.Remove(),
 			rack.Remove(),
 			zone.Remove())
+
+	case Validate:
+		cmd = cobra.Command{
+			Use:   "validate file...",
+			Short: "Check schema files",
+			Long: "Validate checks the files against the schema and checks that the objects they refer to " +
+				"exist in the files, without needing the metal server. With --live they may also exist in the " +
+				"live schema, as load and apply check before changing anything.",
+			Args:         cobra.MinimumNArgs(1),
+			SilenceUsage: true,
+			RunE: func(_ *cobra.Command, args []string) error {
+				if r.live.Val() {
+					_, _, err := r.readFiles(args)
+
+					return err
+				}
+
+				srcs, err := schema.Open(args, r.format.Val())
+				if err != nil {
+					return err
+				}
+
+				return schema.Validate(srcs, nil)
+			},
+		}
+
+		r.format.Add(cmd.Flags())
+		r.live.Add(cmd.Flags())
 	}
 
 	return &cmd
 }
 
 func (r *Root) dump() error {
-	req := pb.ReadSchemaRequest_builder{
-		Zone:    r.zone.Ptr(),
-		Cluster: r.cluster.Ptr(),
-		Host:    r.host.Ptr(),
-	}.Build()
-
-	resp, err := r.Metal.ReadSchema(r.Metal.Context(), req)
+	doc, err := r.liveSchema()
 	if err != nil {
 		return err
 	}
 
-	doc := resp.GetSchema()
+	format := schema.YAML
+	if r.json.Val() {
+		format = schema.JSON
+	}
+
+	if r.split.IsSet() {
+		scope := schema.Scope{
+			Zone:    r.zone.Val(),
+			Cluster: r.cluster.Val(),
+			Host:    r.host.Val(),
+		}
 
-	if !r.json.Val() { // parse json as yaml and re-marshal
-		var obj map[string]any
+		return schema.WriteSplit(r.split.Val(), doc, format, scope)
+	}
 
-		b, err := protojson.MarshalOptions{
-			UseProtoNames: true,
-		}.Marshal(doc)
+	data, err := schema.Marshal(doc, format)
+	if err != nil {
+		return err
+	}
+
+	if r.merge.IsSet() {
+		if r.json.Val() {
+			return errMergeJSON
+		}
+
+		old, err := os.ReadFile(r.merge.Val())
 		if err != nil {
 			return err
 		}
 
-		if err := yaml.Unmarshal(b, &obj); err != nil {
+		if data, err = schema.MergeComments(old, data); err != nil {
 			return err
 		}
 
-		return yaml.NewEncoder(os.Stdout, yaml.IndentSequence(true)).Encode(obj)
+		return os.WriteFile(r.merge.Val(), data, 0o644) //nolint:gosec
 	}
 
-	b, err := protojson.MarshalOptions{
-		Multiline:     true,
-		UseProtoNames: true,
-	}.Marshal(doc)
+	_, err = os.Stdout.Write(data)
+
+	return err
+}
+
+func (r *Root) load(files []string) error {
+	doc, _, err := r.readFiles(files)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(string(b))
+	req := pb.CreateSchemaRequest_builder{
+		Schema: doc,
+	}.Build()
+
+	_, err = r.Metal.CreateSchema(r.Metal.Context(), req)
 
-	return nil
+	return err
 }
 
-func (r *Root) load(filename string) error {
-	fin, err := os.Open(filename)
+// diff prints the changes from the live schema to the one in the files and
+// returns errSchemaDiffers if there are any. Loading never deletes, so live
+// objects missing from the files are not changes.
+func (r *Root) diff(files []string) error {
+	changes, err := r.changes(files)
 	if err != nil {
 		return err
 	}
-	defer fin.Close()
 
-	data, err := io.ReadAll(fin)
-	if err != nil {
+	changes = slices.DeleteFunc(changes, func(c diff.Change) bool {
+		return c.Kind == diff.Removed
+	})
+
+	if err := r.printChanges(changes); err != nil {
 		return err
 	}
 
-	var doc pb.Schema
+	if len(changes) > 0 {
+		return errSchemaDiffers
+	}
 
-	switch filepath.Ext(filename) {
-	case ".json":
-		if err := protojson.Unmarshal(data, &doc); err != nil {
-			return err
-		}
-	case ".yaml", ".yml":
-		var jsonMap map[string]any
+	return nil
+}
 
-		if err := yaml.Unmarshal(data, &jsonMap); err != nil {
-			return fmt.Errorf("failed to unmarshal yaml: %w", err)
-		}
+func (r *Root) changes(files []string) ([]diff.Change, error) {
+	doc, live, err := r.readFiles(files)
+	if err != nil {
+		return nil, err
+	}
 
-		jsonData, err := json.Marshal(jsonMap)
-		if err != nil {
-			return fmt.Errorf("failed to marshal json: %w", err)
-		}
+	return diff.Compare(live, doc), nil
+}
 
-		if err := protojson.Unmarshal(jsonData, &doc); err != nil {
-			return fmt.Errorf("failed to unmarshal json: %w", err)
-		}
+// readFiles validates and merges the files, returning them along with the
+// live schema their references were checked against.
+func (r *Root) readFiles(files []string) (*pb.Schema, *pb.Schema, error) {
+	srcs, err := schema.Open(files, r.format.Val())
+	if err != nil {
+		return nil, nil, err
+	}
 
-	default:
-		return errors.New("unknown file type")
+	live, err := r.liveSchema()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	req := pb.CreateSchemaRequest_builder{
-		Schema: &doc,
+	if err := schema.Validate(srcs, live); err != nil {
+		return nil, nil, err
+	}
+
+	doc, err := schema.Merge(srcs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return doc, live, nil
+}
+
+func (r *Root) printChanges(changes []diff.Change) error {
+	if r.json.Val() {
+		return diff.JSON(os.Stdout, changes)
+	}
+
+	color := term.IsTerminal(int(os.Stdout.Fd())) && os.Getenv("NO_COLOR") == "" //nolint:gosec
+
+	return diff.Unified(os.Stdout, changes, color)
+}
+
+// liveSchema reads the schema from metal, scoped by the zone, cluster and
+// host flags.
+func (r *Root) liveSchema() (*pb.Schema, error) {
+	req := pb.ReadSchemaRequest_builder{
+		Zone:    r.zone.Ptr(),
+		Cluster: r.cluster.Ptr(),
+		Host:    r.host.Ptr(),
 	}.Build()
 
-	_, err = r.Metal.CreateSchema(r.Metal.Context(), req)
+	resp, err := r.Metal.ReadSchema(r.Metal.Context(), req)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	return resp.GetSchema(), nil
+}
+
+func (r *Root) newWriter(name string, row any) (output.Writer, error) {
+	opts := output.Options{
+		Format:    r.output.Val(),
+		Columns:   r.output.Columns(),
+		SortBy:    r.output.SortBy(),
+		NoHeaders: r.output.NoHeaders(),
+		Reverse:   r.output.Reverse(),
+	}
+
+	return output.New(opts, name, row)
+}
+
+func (r *Root) login() error {
+	var (
+		pass string
+		err  error
+	)
+
+	if r.passStdin.Val() {
+		pass, err = session.ReadPassword(os.Stdin)
+	} else {
+		pass, err = r.Session.Password()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return r.Session.Login(pass)
 }
 
 func (r *Root) report(template string) error {
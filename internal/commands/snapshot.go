@@ -0,0 +1,261 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
+	"endobit.io/stack/internal/schema"
+	"endobit.io/stack/internal/snapshot"
+)
+
+type Snapshots struct {
+	*Root
+	store *snapshot.Store
+}
+
+func NewSnapshots(r *Root) *Snapshots {
+	return &Snapshots{Root: r, store: snapshot.NewStore(snapshot.DefaultDir())}
+}
+
+func (s *Snapshots) Save() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "save [name]",
+		Short: "Save the live schema",
+		Long:  "Save stores the live schema in a compressed, timestamped file. The name defaults to the time.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			var name string
+
+			if len(args) > 0 {
+				name = args[0]
+			}
+
+			return s.save(name)
+		},
+	}
+
+	return cmd
+}
+
+func (s *Snapshots) List() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the saved snapshots",
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return s.list()
+		},
+	}
+
+	s.output.Add(cmd.Flags())
+
+	return cmd
+}
+
+func (s *Snapshots) Diff() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff from [to]",
+		Short: "Compare two snapshots",
+		Long: "Diff shows how the schema changed from one snapshot to another, or to the live schema " +
+			"if only one is given, and exits non-zero if it did.",
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return s.diff(args)
+		},
+	}
+
+	s.json.Add(cmd.Flags(), "changes")
+
+	return cmd
+}
+
+func (s *Snapshots) Restore() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore name",
+		Short: "Make the live schema match a snapshot",
+		Long: "Restore creates, updates and deletes objects so the live schema matches the snapshot. " +
+			"With --zone only that zone is restored. It shows the changes and asks before making them, " +
+			"unless --yes is given, and a snapshot of another server needs --force.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return s.restore(args[0])
+		},
+	}
+
+	s.dryRun.Add(cmd.Flags())
+	s.yes.Add(cmd.Flags())
+	s.force.AddServer(cmd.Flags(), "snapshot")
+	s.json.Add(cmd.Flags(), "changes")
+	s.zone.Add(cmd.Flags(), "snapshot", false)
+
+	return cmd
+}
+
+func (s *Snapshots) save(name string) error {
+	doc, err := s.liveSchema()
+	if err != nil {
+		return err
+	}
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	snap := snapshot.Snapshot{
+		Name:   name,
+		Server: s.Session.Server,
+		User:   s.Session.User,
+		Schema: data,
+	}
+
+	if err := s.store.Save(&snap); err != nil {
+		return err
+	}
+
+	fmt.Println(snap.Name)
+
+	return nil
+}
+
+func (s *Snapshots) list() error {
+	type row struct {
+		Snapshot string
+		Time     string
+		Server   string
+		User     string
+	}
+
+	t, err := s.newWriter("snapshot", row{})
+	if err != nil {
+		return err
+	}
+
+	list, err := s.store.List()
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range list {
+		_ = t.Write(row{
+			Snapshot: snap.Name,
+			Time:     snap.Time.Local().Format(time.DateTime),
+			Server:   snap.Server,
+			User:     snap.User,
+		})
+	}
+
+	return t.Flush()
+}
+
+func (s *Snapshots) diff(names []string) error {
+	from, err := s.schema(names[0])
+	if err != nil {
+		return err
+	}
+
+	var to *pb.Schema
+
+	if len(names) > 1 {
+		to, err = s.schema(names[1])
+	} else {
+		to, err = s.liveSchema()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	changes := diff.CompareAll(from, to)
+
+	if err := s.printChanges(changes); err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		return errSchemaDiffers
+	}
+
+	return nil
+}
+
+// restore reconciles the live schema with the snapshot, deleting what has
+// been added since and clearing the fields set since. With --zone the live schema is read and compared for that
+// zone alone.
+func (s *Snapshots) restore(name string) error {
+	snap, err := s.store.Get(name)
+	if err != nil {
+		return err
+	}
+
+	if snap.Server != s.Session.Server && !s.force.Val() {
+		return fmt.Errorf("%w: snapshot %s is of %s, not %s", errOtherServer, name, snap.Server, s.Session.Server)
+	}
+
+	doc, err := decode(snap)
+	if err != nil {
+		return err
+	}
+
+	if s.zone.IsSet() {
+		var ok bool
+
+		if doc, ok = schema.Zone(doc, s.zone.Val()); !ok {
+			return fmt.Errorf("%w: zone %q", errNotInSnapshot, s.zone.Val())
+		}
+	}
+
+	live, err := s.liveSchema()
+	if err != nil {
+		return err
+	}
+
+	// with --zone the rest of the live schema is not in doc, so only the
+	// zone's objects are compared
+	changes := slices.DeleteFunc(diff.CompareAll(live, doc), func(c diff.Change) bool {
+		return !s.inScope(c.Path)
+	})
+
+	if err := s.printChanges(changes); err != nil {
+		return err
+	}
+
+	if s.dryRun.Val() || len(changes) == 0 {
+		return nil
+	}
+
+	ok, err := s.confirm(fmt.Sprintf("Restore %s, making %d changes?", name, len(changes)))
+	if !ok || err != nil {
+		return err
+	}
+
+	return s.execute(changes)
+}
+
+// schema returns the schema in the newest snapshot with the given name.
+func (s *Snapshots) schema(name string) (*pb.Schema, error) {
+	snap, err := s.store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(snap)
+}
+
+func decode(snap snapshot.Snapshot) (*pb.Schema, error) {
+	var doc pb.Schema
+
+	if err := protojson.Unmarshal(snap.Schema, &doc); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", snap.Name, err)
+	}
+
+	return &doc, nil
+}
@@ -0,0 +1,433 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
+	"endobit.io/stack/internal/journal"
+)
+
+// path builds a schema path from pairs of list and name. Pairs with an empty
+// name are left out, so path("zones", z, "clusters", c) is just the zone when
+// no cluster is given.
+func path(pairs ...string) diff.Path {
+	var p diff.Path
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			p = append(p, diff.Step{Field: pairs[i], Key: pairs[i+1]})
+		}
+	}
+
+	return p
+}
+
+// journal reads the objects in list under parent named by names before a
+// command changes or removes them. The command passes its result to the
+// returned done, which records the objects in the journal so that undo can
+// put them back: all of them if the command succeeded, or those it names as
+// affected if the command failed part way.
+func (r *Root) journal(parent diff.Path, list string, names ...string) (func(err error, affected ...string) error, error) {
+	doc, err := r.readObjects(parent, list, names)
+	if err != nil {
+		return nil, err
+	}
+
+	e := journal.Entry{
+		Server:  r.Session.Server,
+		User:    r.Session.User,
+		Command: os.Args,
+		Parent:  parent,
+		List:    list,
+	}
+
+	if len(names) == 1 && r.rename.IsSet() && r.rename.Val() != names[0] {
+		e.Rename = r.rename.Val()
+	}
+
+	done := func(err error, affected ...string) error {
+		if err == nil {
+			affected = names
+		}
+
+		if len(affected) == 0 {
+			return err
+		}
+
+		data, merr := protojson.MarshalOptions{UseProtoNames: true}.Marshal(pick(doc, parent, list, affected))
+		if merr != nil {
+			return errors.Join(err, merr)
+		}
+
+		e.Names, e.Before = affected, data
+
+		return errors.Join(err, journal.NewStore(journal.DefaultDir()).Add(&e))
+	}
+
+	return done, nil
+}
+
+// undo puts back the objects saved by the newest journal entry for the
+// server, or the entry given. Removed objects are created again with their
+// attrs, renamed objects get their old name back, changed fields their old
+// values and fields that were unset before the command are unset again.
+func (r *Root) undo(args []string) error {
+	store := journal.NewStore(journal.DefaultDir())
+
+	if r.list.Val() {
+		return r.listJournal(store)
+	}
+
+	var (
+		e   journal.Entry
+		err error
+	)
+
+	if len(args) > 0 {
+		id, aerr := strconv.Atoi(args[0])
+		if aerr != nil {
+			return fmt.Errorf("invalid journal id %q: %w", args[0], aerr)
+		}
+
+		e, err = store.Get(id)
+	} else {
+		e, err = store.Last(r.Session.Server)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if e.Server != r.Session.Server && !r.force.Val() {
+		return fmt.Errorf("%w: journal entry %d is of %s, not %s", errOtherServer, e.ID, e.Server, r.Session.Server)
+	}
+
+	var before pb.Schema
+
+	if err := protojson.Unmarshal(e.Before, &before); err != nil {
+		return fmt.Errorf("journal entry %d: %w", e.ID, err)
+	}
+
+	names := e.Names
+	if e.Rename != "" {
+		names = append(slices.Clip(names), e.Rename)
+	}
+
+	live, err := r.readObjects(e.Parent, e.List, names)
+	if err != nil {
+		return err
+	}
+
+	var changes []diff.Change
+
+	if e.Rename != "" {
+		// rename first, then compare as if the object had its old name
+		renamed := append(e.Parent[:len(e.Parent):len(e.Parent)], diff.Step{Field: e.List, Key: e.Rename})
+
+		if obj := find(live, renamed); obj != nil {
+			obj.Set(obj.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString(e.Names[0]))
+
+			changes = append(changes, diff.Change{
+				Kind:  diff.Changed,
+				Path:  renamed,
+				Field: "name",
+				Old:   e.Rename,
+				New:   e.Names[0],
+			})
+		}
+	}
+
+	// the entry holds the whole state of its objects, so compare them alone
+	for _, c := range diff.CompareAll(pick(live, e.Parent, e.List, e.Names), &before) {
+		if c.Kind != diff.Removed { // objects outside the entry are not its business
+			changes = append(changes, c)
+		}
+	}
+
+	if err := r.reconcile(changes, false); err != nil {
+		return err
+	}
+
+	if r.dryRun.Val() {
+		return nil
+	}
+
+	return store.Delete(e.ID)
+}
+
+func (r *Root) listJournal(store *journal.Store) error {
+	type row struct {
+		ID      string
+		Time    string
+		Server  string
+		User    string
+		Command string
+	}
+
+	t, err := r.newWriter("id", row{})
+	if err != nil {
+		return err
+	}
+
+	list, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range list {
+		var command string
+
+		if len(e.Command) > 0 {
+			command = strings.Join(e.Command[1:], " ")
+		}
+
+		_ = t.Write(row{
+			ID:      strconv.Itoa(e.ID),
+			Time:    e.Time.Local().Format(time.DateTime),
+			Server:  e.Server,
+			User:    e.User,
+			Command: command,
+		})
+	}
+
+	return t.Flush()
+}
+
+// readObjects reads the objects in list under parent named by names from the
+// live schema, within their parents as pick leaves them. Only what holds them
+// is read: the schema of their zone, or of each zone removed, and makes,
+// models and global attrs object by object, as the schema has no scope for
+// them.
+func (r *Root) readObjects(parent diff.Path, list string, names []string) (*pb.Schema, error) {
+	var out pb.Schema
+
+	switch {
+	case len(parent) == 0 && list == "zones":
+		for _, name := range names {
+			doc, err := r.readSchema(path("zones", name))
+			if err != nil {
+				return nil, err
+			}
+
+			proto.Merge(&out, pick(doc, nil, list, []string{name}))
+		}
+
+		return &out, nil
+	case len(parent) == 0 && list == "attrs":
+		for resp, err := range r.Metal.NewGlobalAttrReader("").Responses() {
+			if err != nil {
+				return nil, err
+			}
+
+			appendObject(out.ProtoReflect(), list, resp)
+		}
+	case len(parent) == 0 && list == "makes":
+		for _, name := range names {
+			if err := r.readMake(&out, name); err != nil {
+				return nil, err
+			}
+		}
+	case len(parent) > 0 && parent[0].Field == "makes":
+		if err := r.readMake(&out, parent[0].Key); err != nil {
+			return nil, err
+		}
+	default:
+		doc, err := r.readSchema(parent)
+		if err != nil {
+			return nil, err
+		}
+
+		return pick(doc, parent, list, names), nil
+	}
+
+	return pick(&out, parent, list, names), nil
+}
+
+// readMake appends the make with its attrs and models, and theirs, to doc.
+func (r *Root) readMake(doc *pb.Schema, name string) error {
+	for resp, err := range r.Metal.NewMakeReader(literal(name)).Responses() {
+		if err != nil {
+			return err
+		}
+
+		if resp.GetName() != name {
+			continue
+		}
+
+		mk := appendObject(doc.ProtoReflect(), "makes", resp)
+
+		for attr, err := range r.Metal.NewMakeAttrReader(name, "").Responses() {
+			if err != nil {
+				return err
+			}
+
+			appendObject(mk, "attrs", attr)
+		}
+
+		for model, err := range r.Metal.NewModelReader(name, "").Responses() {
+			if err != nil {
+				return err
+			}
+
+			md := appendObject(mk, "models", model)
+
+			for attr, err := range r.Metal.NewModelAttrReader(model.GetName(), "").Responses() {
+				if err != nil {
+					return err
+				}
+
+				appendObject(md, "attrs", attr)
+			}
+		}
+	}
+
+	return nil
+}
+
+// appendObject appends an object to the named list of m, filled in from the
+// fields of src with the same name and type, and returns it. It returns nil
+// if m is nil or has no such list.
+func appendObject(m protoreflect.Message, list string, src proto.Message) protoreflect.Message {
+	if m == nil {
+		return nil
+	}
+
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(list))
+	if fd == nil || !fd.IsList() || fd.Message() == nil {
+		return nil
+	}
+
+	l := m.Mutable(fd).List()
+	obj := l.NewElement().Message()
+
+	src.ProtoReflect().Range(func(sfd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if dfd := obj.Descriptor().Fields().ByName(sfd.Name()); dfd != nil && sameType(dfd, sfd) {
+			obj.Set(dfd, v)
+		}
+
+		return true
+	})
+
+	l.Append(protoreflect.ValueOfMessage(obj))
+
+	return obj
+}
+
+func sameType(a, b protoreflect.FieldDescriptor) bool {
+	switch {
+	case a.Kind() != b.Kind() || a.Cardinality() != b.Cardinality() || a.IsMap() || b.IsMap():
+		return false
+	case a.Enum() != nil:
+		return a.Enum().FullName() == b.Enum().FullName()
+	case a.Message() != nil:
+		return a.Message().FullName() == b.Message().FullName()
+	}
+
+	return true
+}
+
+// readSchema reads the part of the live schema holding the objects under
+// parent.
+func (r *Root) readSchema(parent diff.Path) (*pb.Schema, error) {
+	req := pb.ReadSchemaRequest_builder{
+		Zone:    Optional(parent.Key("zones")),
+		Cluster: Optional(parent.Key("clusters")),
+		Host:    Optional(parent.Key("hosts")),
+	}.Build()
+
+	resp, err := r.Metal.ReadSchema(r.Metal.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetSchema(), nil
+}
+
+// pick returns the objects in list under parent named by names, within their
+// parents, which are left with just their names.
+func pick(doc *pb.Schema, parent diff.Path, list string, names []string) *pb.Schema {
+	var out pb.Schema
+
+	src, dst := doc.ProtoReflect(), out.ProtoReflect()
+
+	for _, s := range parent {
+		fd := src.Descriptor().Fields().ByName(protoreflect.Name(s.Field))
+		if fd == nil || !fd.IsList() {
+			return &out
+		}
+
+		el := element(src.Get(fd).List(), s.Key)
+		if el == nil {
+			return &out
+		}
+
+		child := el.New()
+		child.Set(child.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString(s.Key))
+		dst.Mutable(fd).List().Append(protoreflect.ValueOfMessage(child))
+
+		src, dst = el, child
+	}
+
+	fd := src.Descriptor().Fields().ByName(protoreflect.Name(list))
+	if fd == nil || !fd.IsList() {
+		return &out
+	}
+
+	l := src.Get(fd).List()
+
+	for i := range l.Len() {
+		if slices.Contains(names, nameOf(l.Get(i).Message())) {
+			dst.Mutable(fd).List().Append(l.Get(i))
+		}
+	}
+
+	return &out
+}
+
+// find returns the object at path in doc, or nil.
+func find(doc *pb.Schema, path diff.Path) protoreflect.Message {
+	m := doc.ProtoReflect()
+
+	for _, s := range path {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(s.Field))
+		if fd == nil || !fd.IsList() {
+			return nil
+		}
+
+		if m = element(m.Get(fd).List(), s.Key); m == nil {
+			return nil
+		}
+	}
+
+	return m
+}
+
+func element(l protoreflect.List, name string) protoreflect.Message {
+	for i := range l.Len() {
+		if nameOf(l.Get(i).Message()) == name {
+			return l.Get(i).Message()
+		}
+	}
+
+	return nil
+}
+
+func nameOf(m protoreflect.Message) string {
+	fd := m.Descriptor().Fields().ByName("name")
+	if fd == nil {
+		return ""
+	}
+
+	return m.Get(fd).String()
+}
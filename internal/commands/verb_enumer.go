@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _VerbName = "adddumplistloadremovereportset"
+const _VerbName = "addapplyconfigdiffdumplistloadloginlogoutremovereportschemasetsnapshotundounsetvalidate"
 
-var _VerbIndex = [...]uint8{0, 3, 7, 11, 15, 21, 27, 30}
+var _VerbIndex = [...]uint8{0, 3, 8, 14, 18, 22, 26, 30, 35, 41, 47, 53, 59, 62, 70, 74, 79, 87}
 
-const _VerbLowerName = "adddumplistloadremovereportset"
+const _VerbLowerName = "addapplyconfigdiffdumplistloadloginlogoutremovereportschemasetsnapshotundounsetvalidate"
 
 func (i Verb) String() string {
 	if i < 0 || i >= Verb(len(_VerbIndex)-1) {
@@ -25,41 +25,81 @@ func (i Verb) String() string {
 func _VerbNoOp() {
 	var x [1]struct{}
 	_ = x[Add-(0)]
-	_ = x[Dump-(1)]
-	_ = x[List-(2)]
-	_ = x[Load-(3)]
-	_ = x[Remove-(4)]
-	_ = x[Report-(5)]
-	_ = x[Set-(6)]
+	_ = x[Apply-(1)]
+	_ = x[Config-(2)]
+	_ = x[Diff-(3)]
+	_ = x[Dump-(4)]
+	_ = x[List-(5)]
+	_ = x[Load-(6)]
+	_ = x[Login-(7)]
+	_ = x[Logout-(8)]
+	_ = x[Remove-(9)]
+	_ = x[Report-(10)]
+	_ = x[Schema-(11)]
+	_ = x[Set-(12)]
+	_ = x[Snapshot-(13)]
+	_ = x[Undo-(14)]
+	_ = x[Unset-(15)]
+	_ = x[Validate-(16)]
 }
 
-var _VerbValues = []Verb{Add, Dump, List, Load, Remove, Report, Set}
+var _VerbValues = []Verb{Add, Apply, Config, Diff, Dump, List, Load, Login, Logout, Remove, Report, Schema, Set, Snapshot, Undo, Unset, Validate}
 
 var _VerbNameToValueMap = map[string]Verb{
 	_VerbName[0:3]:        Add,
 	_VerbLowerName[0:3]:   Add,
-	_VerbName[3:7]:        Dump,
-	_VerbLowerName[3:7]:   Dump,
-	_VerbName[7:11]:       List,
-	_VerbLowerName[7:11]:  List,
-	_VerbName[11:15]:      Load,
-	_VerbLowerName[11:15]: Load,
-	_VerbName[15:21]:      Remove,
-	_VerbLowerName[15:21]: Remove,
-	_VerbName[21:27]:      Report,
-	_VerbLowerName[21:27]: Report,
-	_VerbName[27:30]:      Set,
-	_VerbLowerName[27:30]: Set,
+	_VerbName[3:8]:        Apply,
+	_VerbLowerName[3:8]:   Apply,
+	_VerbName[8:14]:       Config,
+	_VerbLowerName[8:14]:  Config,
+	_VerbName[14:18]:      Diff,
+	_VerbLowerName[14:18]: Diff,
+	_VerbName[18:22]:      Dump,
+	_VerbLowerName[18:22]: Dump,
+	_VerbName[22:26]:      List,
+	_VerbLowerName[22:26]: List,
+	_VerbName[26:30]:      Load,
+	_VerbLowerName[26:30]: Load,
+	_VerbName[30:35]:      Login,
+	_VerbLowerName[30:35]: Login,
+	_VerbName[35:41]:      Logout,
+	_VerbLowerName[35:41]: Logout,
+	_VerbName[41:47]:      Remove,
+	_VerbLowerName[41:47]: Remove,
+	_VerbName[47:53]:      Report,
+	_VerbLowerName[47:53]: Report,
+	_VerbName[53:59]:      Schema,
+	_VerbLowerName[53:59]: Schema,
+	_VerbName[59:62]:      Set,
+	_VerbLowerName[59:62]: Set,
+	_VerbName[62:70]:      Snapshot,
+	_VerbLowerName[62:70]: Snapshot,
+	_VerbName[70:74]:      Undo,
+	_VerbLowerName[70:74]: Undo,
+	_VerbName[74:79]:      Unset,
+	_VerbLowerName[74:79]: Unset,
+	_VerbName[79:87]:      Validate,
+	_VerbLowerName[79:87]: Validate,
 }
 
 var _VerbNames = []string{
 	_VerbName[0:3],
-	_VerbName[3:7],
-	_VerbName[7:11],
-	_VerbName[11:15],
-	_VerbName[15:21],
-	_VerbName[21:27],
-	_VerbName[27:30],
+	_VerbName[3:8],
+	_VerbName[8:14],
+	_VerbName[14:18],
+	_VerbName[18:22],
+	_VerbName[22:26],
+	_VerbName[26:30],
+	_VerbName[30:35],
+	_VerbName[35:41],
+	_VerbName[41:47],
+	_VerbName[47:53],
+	_VerbName[53:59],
+	_VerbName[59:62],
+	_VerbName[62:70],
+	_VerbName[70:74],
+	_VerbName[74:79],
+	_VerbName[79:87],
 }
 
 // VerbString retrieves an enum value from the enum constants string name.
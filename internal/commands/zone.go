@@ -4,8 +4,8 @@ import (
 	"github.com/spf13/cobra"
 
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
+	"endobit.io/stack/internal/diff"
 	"endobit.io/stack/internal/flags/set"
-	"endobit.io/table"
 )
 
 type Zone struct {
@@ -42,6 +42,8 @@ func (z *Zone) Add() *cobra.Command {
 
 	z.timezone.Add(cmd.Flags(), zone)
 
+	cmd.AddCommand(NewZoneAttr(z).Add())
+
 	return cmd
 }
 
@@ -51,13 +53,20 @@ func (z *Zone) Set() *cobra.Command {
 		Short: "Set a " + zone + "'s properties",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return z.update(args[0])
+			done, err := z.journal(nil, "zones", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(z.update(args[0]))
 		},
 	}
 
 	z.timezone.Add(cmd.Flags(), zone)
 	z.rename.Add(cmd.Flags(), zone)
 
+	cmd.AddCommand(NewZoneAttr(z).Set())
+
 	return cmd
 }
 
@@ -76,6 +85,10 @@ func (z *Zone) List() *cobra.Command {
 		},
 	}
 
+	z.output.Add(cmd.Flags())
+
+	cmd.AddCommand(NewZoneAttr(z).List())
+
 	return cmd
 }
 
@@ -89,6 +102,12 @@ func (z *Zone) Remove() *cobra.Command {
 		},
 	}
 
+	z.dryRun.Add(cmd.Flags())
+	z.yes.Add(cmd.Flags())
+	z.force.Add(cmd.Flags(), zone)
+
+	cmd.AddCommand(NewZoneAttr(z).Remove())
+
 	return cmd
 }
 
@@ -103,8 +122,10 @@ func (z *Zone) create(zone string) error {
 
 func (z *Zone) list(glob string) error {
 	type row struct{ Zone, TimeZone string }
-	t := table.New()
-	defer t.Flush()
+	t, err := z.newWriter(zone, row{})
+	if err != nil {
+		return err
+	}
 
 	r := z.Metal.NewZoneReader(glob)
 
@@ -119,7 +140,7 @@ func (z *Zone) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (z *Zone) update(zone string) error {
@@ -137,25 +158,43 @@ func (z *Zone) update(zone string) error {
 }
 
 func (z *Zone) remove(glob string) error {
-	var req pb.DeleteZonesRequest
+	names, err := matches(z.Metal.NewZoneReader(glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	req.SetGlob(glob)
-	_, err := z.Metal.DeleteZones(z.Metal.Context(), &req)
+	if ok, err := z.confirmRemove(zone, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := z.journal(nil, "zones", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		var req pb.DeleteZonesRequest
+
+		req.SetGlob(literal(name))
+		if _, err := z.Metal.DeleteZones(z.Metal.Context(), &req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
 
 func (a *ZoneAttr) Add() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   attribute + " name",
+		Use:   attribute + " name value",
 		Short: "Add an " + attribute + " to a " + zone,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.ExactArgs(2),
 		RunE: func(_ *cobra.Command, args []string) error {
 			if err := a.create(args[0]); err != nil {
 				return err
 			}
 
-			return a.update(args[0])
+			return a.update(args[0], args[1])
 		},
 	}
 
@@ -166,15 +205,27 @@ func (a *ZoneAttr) Add() *cobra.Command {
 
 func (a *ZoneAttr) Set() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   attribute + " name",
+		Use:   attribute + " name [value]",
 		Short: "Set a " + zone + " " + attribute + "'s properties",
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(_ *cobra.Command, args []string) error {
-			return a.update(args[0])
+			var value string
+
+			if len(args) > 1 {
+				value = args[1]
+			}
+
+			done, err := a.journal(a.parent(), "attrs", args[0])
+			if err != nil {
+				return err
+			}
+
+			return done(a.update(args[0], value))
 		},
 	}
 
 	a.zone.Add(cmd.Flags(), zone, true)
+	a.rename.Add(cmd.Flags(), attribute)
 
 	return cmd
 }
@@ -195,7 +246,7 @@ func (a *ZoneAttr) List() *cobra.Command {
 	}
 
 	a.zone.Add(cmd.Flags(), zone, false)
-	a.rename.Add(cmd.Flags(), zone)
+	a.output.Add(cmd.Flags())
 
 	return cmd
 }
@@ -211,6 +262,11 @@ func (a *ZoneAttr) Remove() *cobra.Command {
 	}
 
 	a.zone.Add(cmd.Flags(), zone, true)
+
+	a.dryRun.Add(cmd.Flags())
+	a.yes.Add(cmd.Flags())
+	a.force.Add(cmd.Flags(), zone+" "+attribute)
+
 	return cmd
 }
 
@@ -227,8 +283,10 @@ func (a *ZoneAttr) create(attr string) error {
 
 func (a *ZoneAttr) list(glob string) error {
 	type row struct{ Zone, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.newWriter(attribute, row{})
+	if err != nil {
+		return err
+	}
 
 	r := a.Metal.NewZoneAttrReader(a.zone.Val(), glob)
 
@@ -244,15 +302,22 @@ func (a *ZoneAttr) list(glob string) error {
 		})
 	}
 
-	return nil
+	return t.Flush()
 }
 
-func (a *ZoneAttr) update(attr string) error {
+func (a *ZoneAttr) update(attr, val string) error {
+	var value *string
+
+	if val != "" {
+		value = &val
+	}
+
 	req := pb.UpdateZoneAttrRequest_builder{
 		Zone: a.zone.Ptr(),
 		Name: &attr,
 		Fields: pb.UpdateZoneAttrRequest_Fields_builder{
-			Name: a.rename.Ptr(),
+			Name:  a.rename.Ptr(),
+			Value: value,
 		}.Build(),
 	}.Build()
 
@@ -261,13 +326,37 @@ func (a *ZoneAttr) update(attr string) error {
 	return err
 }
 
+// parent is where the zone attrs are in the schema.
+func (a *ZoneAttr) parent() diff.Path {
+	return path("zones", a.zone.Val())
+}
+
 func (a *ZoneAttr) remove(glob string) error {
-	req := pb.DeleteZoneAttrsRequest_builder{
-		Zone: a.zone.Ptr(),
-		Glob: &glob,
-	}.Build()
+	names, err := matches(a.Metal.NewZoneAttrReader(a.zone.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
 
-	_, err := a.Metal.DeleteZoneAttrs(a.Metal.Context(), req)
+	if ok, err := a.confirmRemove(zone+" "+attribute, names); !ok || err != nil {
+		return err
+	}
 
-	return err
+	done, err := a.journal(a.parent(), "attrs", names...)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names {
+		glob := literal(name)
+		req := pb.DeleteZoneAttrsRequest_builder{
+			Zone: a.zone.Ptr(),
+			Glob: &glob,
+		}.Build()
+
+		if _, err := a.Metal.DeleteZoneAttrs(a.Metal.Context(), req); err != nil {
+			return done(err, names[:i]...)
+		}
+	}
+
+	return done(nil)
 }
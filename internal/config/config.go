@@ -0,0 +1,181 @@
+// Package config reads and writes the stack client configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Config is the on-disk client configuration. It holds a set of named
+// contexts and the name of the one used when --context is not given.
+type Config struct {
+	CurrentContext string    `yaml:"current_context,omitempty"`
+	Contexts       []Context `yaml:"contexts,omitempty"`
+
+	path string
+}
+
+// Context is a named connection profile.
+type Context struct {
+	Name       string     `yaml:"name"`
+	Metal      string     `yaml:"metal,omitempty"`
+	Mops       string     `yaml:"mops,omitempty"`
+	User       string     `yaml:"user,omitempty"`
+	Credential Credential `yaml:"credential,omitempty"`
+	TLS        TLS        `yaml:"tls,omitempty"`
+	Zone       string     `yaml:"zone,omitempty"`
+	AuditLog   string     `yaml:"audit_log,omitempty"`
+
+	// RemoveLimit is the most objects a remove may match without --force.
+	// Zero means the default.
+	RemoveLimit int `yaml:"remove_limit,omitempty"`
+}
+
+// Credential says where the password for a context's user comes from. At most
+// one source should be set.
+type Credential struct {
+	Env     string `yaml:"env,omitempty"`
+	Command string `yaml:"command,omitempty"`
+}
+
+var errNoContext = errors.New("context not found")
+
+// DefaultPath returns the location of the configuration file, honoring
+// STACK_CONFIG and XDG_CONFIG_HOME.
+func DefaultPath() string {
+	if p := os.Getenv("STACK_CONFIG"); p != "" {
+		return p
+	}
+
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "stack", "config.yaml")
+}
+
+// Load reads the configuration file at path. A missing file is not an error
+// and yields an empty configuration that will be created on Save.
+func Load(path string) (*Config, error) {
+	cfg := Config{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &cfg, nil
+		}
+
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// Save writes the configuration back to the file it was loaded from.
+func (c *Config) Save() error {
+	data, err := yaml.MarshalWithOptions(c, yaml.IndentSequence(true))
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, data, 0o600)
+}
+
+// Context returns the named context. An empty name selects the current
+// context, and if there is no current context a zero Context is returned.
+func (c *Config) Context(name string) (*Context, error) {
+	if name == "" {
+		if c.CurrentContext == "" {
+			return &Context{}, nil
+		}
+
+		name = c.CurrentContext
+	}
+
+	i := c.index(name)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %q", errNoContext, name)
+	}
+
+	return &c.Contexts[i], nil
+}
+
+// SetContext adds ctx, replacing any existing context of the same name.
+func (c *Config) SetContext(ctx Context) {
+	if i := c.index(ctx.Name); i >= 0 {
+		c.Contexts[i] = ctx
+		return
+	}
+
+	c.Contexts = append(c.Contexts, ctx)
+	slices.SortFunc(c.Contexts, func(a, b Context) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// UseContext makes the named context the current one.
+func (c *Config) UseContext(name string) error {
+	if c.index(name) < 0 {
+		return fmt.Errorf("%w: %q", errNoContext, name)
+	}
+
+	c.CurrentContext = name
+
+	return nil
+}
+
+func (c *Config) index(name string) int {
+	return slices.IndexFunc(c.Contexts, func(ctx Context) bool {
+		return ctx.Name == name
+	})
+}
+
+// IsZero reports whether no password source is configured.
+func (c Credential) IsZero() bool {
+	return c.Env == "" && c.Command == ""
+}
+
+// Password resolves the password from the configured source. The second
+// return value is false if no source is configured.
+func (c Credential) Password() (string, bool, error) {
+	switch {
+	case c.Env != "":
+		pass, ok := os.LookupEnv(c.Env)
+		if !ok {
+			return "", false, fmt.Errorf("password variable %s is not set", c.Env)
+		}
+
+		return pass, true, nil
+
+	case c.Command != "":
+		out, err := exec.Command("sh", "-c", c.Command).Output()
+		if err != nil {
+			return "", false, fmt.Errorf("password command failed: %w", err)
+		}
+
+		return strings.TrimRight(string(out), "\r\n"), true, nil
+	}
+
+	return "", false, nil
+}
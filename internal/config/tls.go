@@ -0,0 +1,60 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLS holds the transport security settings for a context.
+type TLS struct {
+	CACert     string `yaml:"ca_cert,omitempty"`
+	ClientCert string `yaml:"client_cert,omitempty"`
+	ClientKey  string `yaml:"client_key,omitempty"`
+	ServerName string `yaml:"server_name,omitempty"`
+	Insecure   bool   `yaml:"insecure,omitempty"`
+}
+
+var errClientKeyPair = errors.New("client certificate and key must be set together")
+
+// Config builds a tls.Config from the settings. Server certificates are
+// verified against the CA bundle, or the system roots if none is given,
+// unless Insecure is set.
+func (t TLS) Config() (*tls.Config, error) {
+	cfg := tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.Insecure, //nolint:gosec
+	}
+
+	if t.CACert != "" {
+		pem, err := os.ReadFile(t.CACert)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.CACert)
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if (t.ClientCert == "") != (t.ClientKey == "") {
+		return nil, errClientKeyPair
+	}
+
+	if t.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(t.ClientCert, t.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return &cfg, nil
+}
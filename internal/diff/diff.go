@@ -0,0 +1,305 @@
+// Package diff compares two schemas object by object. Objects are the
+// elements of repeated message fields and are matched by their name field.
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// keyField is the field that identifies an element of a repeated message
+// field.
+const keyField = "name"
+
+// Step is one hop from the schema root, a repeated field and the name of the
+// element within it. Singular message fields have an empty Key.
+type Step struct {
+	Field string
+	Key   string
+}
+
+// Path locates an object in the schema.
+type Path []Step
+
+func (p Path) String() string {
+	var b strings.Builder
+
+	for i, s := range p {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+
+		b.WriteString(s.Field)
+
+		if s.Key != "" {
+			b.WriteString("[" + s.Key + "]")
+		}
+	}
+
+	return b.String()
+}
+
+// Key returns the name of the object in the field, or the empty string if the
+// path does not pass through it.
+func (p Path) Key(field string) string {
+	for _, s := range p {
+		if s.Field == field {
+			return s.Key
+		}
+	}
+
+	return ""
+}
+
+// Change is a single difference. Added and Removed changes carry the whole
+// object, Changed carries one field of an object in both its forms.
+type Change struct {
+	Kind   Kind
+	Path   Path
+	Field  string
+	Old    string
+	New    string
+	Object protoreflect.Message
+}
+
+// Compare returns the changes that turn from into to. Only the fields that
+// are set in to are compared, so a file that leaves a field or a list of
+// objects out does not manage it. Changes are sorted by path.
+func Compare(from, to proto.Message) []Change {
+	return sorted(from, to, false)
+}
+
+// CompareAll is Compare for the whole state held in to: the fields and lists
+// that to leaves out are compared too, so objects missing from to are Removed
+// and fields unset in to are Changed with an empty New.
+func CompareAll(from, to proto.Message) []Change {
+	return sorted(from, to, true)
+}
+
+func sorted(from, to proto.Message, all bool) []Change {
+	var changes []Change
+
+	compare(&changes, nil, from.ProtoReflect(), to.ProtoReflect(), all)
+
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Path.String(), b.Path.String())
+	})
+
+	return changes
+}
+
+// Expand follows each added object with the changes that fill it in from
+// empty: its fields as Changed and its children as Added, recursively. This
+// is the order needed to create the objects one at a time.
+func Expand(changes []Change) []Change {
+	var out []Change
+
+	for _, c := range changes {
+		out = append(out, c)
+
+		if c.Kind != Added {
+			continue
+		}
+
+		var fill []Change
+
+		compare(&fill, c.Path, c.Object.Type().Zero(), c.Object, false)
+
+		for _, f := range Expand(fill) {
+			if f.Kind == Changed && f.Field == keyField && slices.Equal(f.Path, c.Path) {
+				continue // already named by the Added change
+			}
+
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
+
+// Order sorts changes so that objects are created and updated after the
+// objects they depend on, and deleted before them. fields lists the fields
+// holding each kind of object, dependencies first; objects in fields not
+// listed come last. Deletes follow all the other changes, in reverse order.
+// Changes of the same kind of object keep their order.
+func Order(changes []Change, fields []string) []Change {
+	rank := func(c Change) int {
+		if len(c.Path) > 0 {
+			if i := slices.Index(fields, c.Path[len(c.Path)-1].Field); i >= 0 {
+				return i
+			}
+		}
+
+		return len(fields)
+	}
+
+	out := slices.Clone(changes)
+
+	slices.SortStableFunc(out, func(a, b Change) int {
+		switch {
+		case a.Kind == Removed && b.Kind == Removed:
+			return rank(b) - rank(a)
+		case a.Kind == Removed:
+			return 1
+		case b.Kind == Removed:
+			return -1
+		}
+
+		return rank(a) - rank(b)
+	})
+
+	return out
+}
+
+// compare appends the changes between from and to. Fields unset in to are
+// skipped unless all is given, in which case only fields unset in both are.
+func compare(changes *[]Change, path Path, from, to protoreflect.Message, all bool) {
+	fields := to.Descriptor().Fields()
+
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		if !has(to, fd) && (!all || !has(from, fd)) {
+			continue
+		}
+
+		switch {
+		case fd.IsList() && keyed(fd):
+			compareList(changes, path, fd, from.Get(fd).List(), to.Get(fd).List(), all)
+		case fd.Message() != nil && !fd.IsList() && !fd.IsMap():
+			compare(changes, append(slices.Clip(path), Step{Field: string(fd.Name())}),
+				from.Get(fd).Message(), to.Get(fd).Message(), all)
+		default:
+			o, n := format(from, fd), format(to, fd)
+			if o != n {
+				*changes = append(*changes, Change{
+					Kind:  Changed,
+					Path:  path,
+					Field: string(fd.Name()),
+					Old:   o,
+					New:   n,
+				})
+			}
+		}
+	}
+}
+
+func compareList(changes *[]Change, path Path, fd protoreflect.FieldDescriptor, from, to protoreflect.List, all bool) {
+	field := string(fd.Name())
+	froms := index(from)
+	seen := make(map[string]bool)
+
+	for i := range to.Len() {
+		m := to.Get(i).Message()
+		key := name(m)
+		seen[key] = true
+		p := append(slices.Clip(path), Step{Field: field, Key: key})
+
+		if o, ok := froms[key]; ok {
+			compare(changes, p, o, m, all)
+		} else {
+			*changes = append(*changes, Change{Kind: Added, Path: p, Object: m})
+		}
+	}
+
+	for i := range from.Len() {
+		m := from.Get(i).Message()
+		if key := name(m); !seen[key] {
+			p := append(slices.Clip(path), Step{Field: field, Key: key})
+			*changes = append(*changes, Change{Kind: Removed, Path: p, Object: m})
+		}
+	}
+}
+
+func has(m protoreflect.Message, fd protoreflect.FieldDescriptor) bool {
+	return m.IsValid() && m.Has(fd)
+}
+
+// keyed reports whether fd is a list of messages with a name.
+func keyed(fd protoreflect.FieldDescriptor) bool {
+	if fd.Message() == nil {
+		return false
+	}
+
+	key := fd.Message().Fields().ByName(keyField)
+
+	return key != nil && key.Kind() == protoreflect.StringKind && !key.IsList()
+}
+
+func index(l protoreflect.List) map[string]protoreflect.Message {
+	m := make(map[string]protoreflect.Message, l.Len())
+
+	for i := range l.Len() {
+		msg := l.Get(i).Message()
+		m[name(msg)] = msg
+	}
+
+	return m
+}
+
+func name(m protoreflect.Message) string {
+	return m.Get(m.Descriptor().Fields().ByName(keyField)).String()
+}
+
+// format renders a field value for display and comparison.
+func format(m protoreflect.Message, fd protoreflect.FieldDescriptor) string {
+	if !m.IsValid() || !m.Has(fd) {
+		return ""
+	}
+
+	v := m.Get(fd)
+
+	switch {
+	case fd.IsList():
+		vals := make([]string, v.List().Len())
+		for i := range v.List().Len() {
+			vals[i] = scalar(fd, v.List().Get(i))
+		}
+
+		return "[" + strings.Join(vals, ", ") + "]"
+	case fd.IsMap():
+		var vals []string
+
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			vals = append(vals, k.String()+": "+scalar(fd.MapValue(), v))
+			return true
+		})
+		slices.Sort(vals)
+
+		return "{" + strings.Join(vals, ", ") + "}"
+	default:
+		return scalar(fd, v)
+	}
+}
+
+func scalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(v.Message().Interface())
+		if err != nil {
+			return fmt.Sprint(v.Message().Interface())
+		}
+
+		return string(b)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+
+		return fmt.Sprint(v.Enum())
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,100 @@
+package diff
+
+import (
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestOrder(t *testing.T) {
+	var (
+		zone    = Path{{"zones", "z1"}}
+		rack    = append(slices.Clip(zone), Step{"racks", "r1"})
+		oldRack = append(slices.Clip(zone), Step{"racks", "r0"})
+		env     = Path{{"environments", "e1"}}
+		cluster = append(slices.Clip(zone), Step{"clusters", "c1"})
+		host    = append(slices.Clip(cluster), Step{"hosts", "h1"})
+		oldHost = append(slices.Clip(cluster), Step{"hosts", "h0"})
+		attr    = append(slices.Clip(host), Step{"attrs", "a1"})
+	)
+
+	// As Compare and Expand leave them for an existing zone: sorted by path,
+	// with each added object followed by its fields.
+	changes := []Change{
+		{Kind: Added, Path: env},
+		{Kind: Added, Path: host},
+		{Kind: Changed, Path: host, Field: "rack", New: "r1"},
+		{Kind: Changed, Path: host, Field: "environment", New: "e1"},
+		{Kind: Added, Path: attr},
+		{Kind: Removed, Path: oldHost},
+		{Kind: Added, Path: rack},
+		{Kind: Removed, Path: oldRack},
+	}
+
+	want := []Change{
+		{Kind: Added, Path: rack},
+		{Kind: Added, Path: env},
+		{Kind: Added, Path: host},
+		{Kind: Changed, Path: host, Field: "rack", New: "r1"},
+		{Kind: Changed, Path: host, Field: "environment", New: "e1"},
+		{Kind: Added, Path: attr},
+		{Kind: Removed, Path: oldHost},
+		{Kind: Removed, Path: oldRack},
+	}
+
+	fields := []string{"zones", "racks", "environments", "clusters", "hosts", "attrs"}
+
+	got := Order(changes, fields)
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i].Kind != want[i].Kind || got[i].Path.String() != want[i].Path.String() || got[i].Field != want[i].Field {
+			t.Errorf("change %d: got %s %s %s, want %s %s %s", i,
+				got[i].Kind, got[i].Path, got[i].Field, want[i].Kind, want[i].Path, want[i].Field)
+		}
+	}
+}
+
+func TestCompareAll(t *testing.T) {
+	from := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("f1"),
+		Package: proto.String("p1"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("m1")},
+			{Name: proto.String("m2")},
+		},
+	}
+
+	// the list is emptied and the package unset
+	to := &descriptorpb.FileDescriptorProto{Name: proto.String("f1")}
+
+	if got := Compare(from, to); len(got) != 0 {
+		t.Errorf("Compare: got %d changes, want none", len(got))
+	}
+
+	want := []Change{
+		{Kind: Changed, Field: "package", Old: "p1"},
+		{Kind: Removed, Path: Path{{"message_type", "m1"}}},
+		{Kind: Removed, Path: Path{{"message_type", "m2"}}},
+	}
+
+	got := CompareAll(from, to)
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if got[i].Kind != want[i].Kind || got[i].Path.String() != want[i].Path.String() ||
+			got[i].Field != want[i].Field || got[i].Old != want[i].Old || got[i].New != want[i].New {
+			t.Errorf("change %d: got %s %s %s %q -> %q, want %s %s %s %q -> %q", i,
+				got[i].Kind, got[i].Path, got[i].Field, got[i].Old, got[i].New,
+				want[i].Kind, want[i].Path, want[i].Field, want[i].Old, want[i].New)
+		}
+	}
+}
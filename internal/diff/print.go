@@ -0,0 +1,135 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	reset  = "\x1b[0m"
+)
+
+// Unified writes the changes grouped by object, prefixing added lines with +
+// and removed lines with -. Added and removed objects are shown in full as
+// YAML.
+func Unified(w io.Writer, changes []Change, color bool) error {
+	paint := func(c, s string) string {
+		if !color {
+			return s
+		}
+
+		return c + s + reset
+	}
+
+	var last string
+
+	for _, c := range changes {
+		path := c.Path.String()
+		if path == "" {
+			path = "."
+		}
+
+		switch c.Kind {
+		case Added, Removed:
+			sign, col := "+", green
+			if c.Kind == Removed {
+				sign, col = "-", red
+			}
+
+			doc, err := toYAML(c)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(w, paint(col, sign+" "+path))
+
+			for _, line := range strings.Split(strings.TrimRight(doc, "\n"), "\n") {
+				fmt.Fprintln(w, paint(col, sign+"   "+line))
+			}
+
+			last = ""
+
+		case Changed:
+			if path != last {
+				fmt.Fprintln(w, paint(yellow, "~ "+path))
+				last = path
+			}
+
+			if c.Old != "" {
+				fmt.Fprintln(w, paint(red, "-   "+c.Field+": "+c.Old))
+			}
+
+			if c.New != "" {
+				fmt.Fprintln(w, paint(green, "+   "+c.Field+": "+c.New))
+			}
+		}
+	}
+
+	return nil
+}
+
+type jsonChange struct {
+	Kind   Kind            `json:"kind"`
+	Path   string          `json:"path"`
+	Field  string          `json:"field,omitempty"`
+	Old    string          `json:"old,omitempty"`
+	New    string          `json:"new,omitempty"`
+	Object json.RawMessage `json:"object,omitempty"`
+}
+
+// JSON writes the changes as a JSON list.
+func JSON(w io.Writer, changes []Change) error {
+	out := make([]jsonChange, len(changes))
+
+	for i, c := range changes {
+		out[i] = jsonChange{
+			Kind:  c.Kind,
+			Path:  c.Path.String(),
+			Field: c.Field,
+			Old:   c.Old,
+			New:   c.New,
+		}
+
+		if c.Object != nil {
+			b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(c.Object.Interface())
+			if err != nil {
+				return err
+			}
+
+			out[i].Object = b
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(out)
+}
+
+func toYAML(c Change) (string, error) {
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(c.Object.Interface())
+	if err != nil {
+		return "", err
+	}
+
+	var obj yaml.MapSlice
+
+	if err := yaml.UnmarshalWithOptions(b, &obj, yaml.UseOrderedMap()); err != nil {
+		return "", err
+	}
+
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
@@ -3,20 +3,57 @@ package flags
 const (
 	Appliance   = "appliance"
 	Arch        = "arch"
+	AuditLog    = "audit-log"
+	Bond        = "bond"
+	CACert      = "ca-cert"
+	ClientCert  = "client-cert"
+	ClientKey   = "client-key"
 	Cluster     = "cluster"
+	Columns     = "columns"
+	Config      = "config"
+	Context     = "context"
+	Default     = "default"
+	DryRun      = "dry-run"
 	Environment = "environment"
+	Force       = "force"
+	Format      = "format"
+	Gateway     = "gateway"
 	Host        = "host"
 	HostType    = "type"
+	Insecure    = "insecure"
+	IP          = "ip"
 	JSON        = "json"
+	List        = "list"
+	Live        = "live"
 	Location    = "location"
+	MAC         = "mac"
 	Make        = "make"
+	Merge       = "merge"
+	Metal       = "metal"
+	MetalUser   = "metal-user"
 	Model       = "model"
+	Mops        = "mops"
+	MTU         = "mtu"
+	Network     = "network"
+	NoHeaders   = "no-headers"
+	Output      = "output"
+	PassCommand = "password-command"
+	PassEnv     = "password-env"
+	PassStdin   = "password-stdin"
+	Prune       = "prune"
 	Rack        = "rack"
 	Rank        = "rank"
 	Rename      = "name"
+	Reverse     = "reverse"
+	ServerName  = "server-name"
 	Slot        = "slot"
+	SortBy      = "sort-by"
+	Split       = "split"
+	Subnet      = "subnet"
 	Template    = "template"
 	TimeZone    = "timezone"
 	Value       = "value"
+	VLAN        = "vlan"
+	Yes         = "yes"
 	Zone        = "zone"
 )
@@ -1,8 +1,6 @@
 package set
 
 import (
-	"os"
-
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
@@ -18,21 +16,49 @@ type (
 
 	Appliance   struct{ flag[string] }
 	Arch        struct{ flag[string] }
+	Bond        struct{ flag[string] }
 	Cluster     struct{ flag[string] }
+	Default     struct{ flag[bool] }
+	DryRun      struct{ flag[bool] }
 	Environment struct{ flag[string] }
+	Force       struct{ flag[bool] }
+	Format      struct{ flag[string] }
+	Gateway     struct{ flag[string] }
 	Host        struct{ flag[string] }
+	IP          struct{ flag[string] }
 	JSON        struct{ flag[bool] }
+	List        struct{ flag[bool] }
+	Live        struct{ flag[bool] }
 	Location    struct{ flag[string] }
+	MAC         struct{ flag[string] }
 	Make        struct{ flag[string] }
+	Merge       struct{ flag[string] }
 	Model       struct{ flag[string] }
+	MTU         struct{ flag[uint32] }
+	Network     struct{ flag[string] }
+	Output      struct {
+		flag[string]
+		columns   []string
+		sortBy    []string
+		noHeaders bool
+		reverse   bool
+	}
+	PassCommand struct{ flag[string] }
+	PassEnv     struct{ flag[string] }
+	PassStdin   struct{ flag[bool] }
+	Prune       struct{ flag[bool] }
 	Rack        struct{ flag[string] }
 	Rank        struct{ flag[uint32] }
 	Rename      struct{ flag[string] }
 	Slot        struct{ flag[uint32] }
+	Split       struct{ flag[string] }
+	Subnet      struct{ flag[string] }
 	Template    struct{ flag[string] }
 	TimeZone    struct{ flag[string] }
 	HostType    struct{ flag[string] }
 	Value       struct{ flag[string] }
+	VLAN        struct{ flag[uint32] }
+	Yes         struct{ flag[bool] }
 	Zone        struct{ flag[string] }
 )
 
@@ -77,16 +103,51 @@ func (a *Arch) Add(fs *pflag.FlagSet, object string) {
 	addString(fs, &a.value, a.name, "architecture for the "+object, false)
 }
 
+func (b *Bond) Add(fs *pflag.FlagSet, object string) {
+	b.name = flags.Bond
+	addString(fs, &b.value, b.name, "bond the "+object+" is a member of", false)
+}
+
 func (c *Cluster) Add(fs *pflag.FlagSet, object string, req bool) {
 	c.name = flags.Cluster
 	addString(fs, &c.value, c.name, "cluster for the "+object, req)
 }
 
+func (d *Default) Add(fs *pflag.FlagSet, object string) {
+	d.name = flags.Default
+	addBool(fs, &d.value, d.name, "use the "+object+" for the default route")
+}
+
+func (d *DryRun) Add(fs *pflag.FlagSet) {
+	d.name = flags.DryRun
+	addBool(fs, &d.value, d.name, "show the changes without making them")
+}
+
 func (e *Environment) Add(fs *pflag.FlagSet, object string, req bool) {
 	e.name = flags.Environment
 	addString(fs, &e.value, e.name, "environment for the "+object, req)
 }
 
+func (f *Force) Add(fs *pflag.FlagSet, object string) {
+	f.name = flags.Force
+	addBool(fs, &f.value, f.name, "remove more "+object+"s than the limit")
+}
+
+func (f *Force) AddServer(fs *pflag.FlagSet, object string) {
+	f.name = flags.Force
+	addBool(fs, &f.value, f.name, "use a "+object+" of another server")
+}
+
+func (f *Format) Add(fs *pflag.FlagSet) {
+	f.name = flags.Format
+	addString(fs, &f.value, f.name, "format of the input files: json|yaml, guessed if not set", false)
+}
+
+func (g *Gateway) Add(fs *pflag.FlagSet, object string) {
+	g.name = flags.Gateway
+	addString(fs, &g.value, g.name, "gateway address for the "+object, false)
+}
+
 func (h *Host) Add(fs *pflag.FlagSet, object string, req bool) {
 	h.name = flags.Host
 	addString(fs, &h.value, h.name, "host for the "+object, req)
@@ -97,21 +158,101 @@ func (h *HostType) Add(fs *pflag.FlagSet, object string) {
 	addString(fs, &h.value, h.name, "type for the "+object, false)
 }
 
+func (i *IP) Add(fs *pflag.FlagSet, object string) {
+	i.name = flags.IP
+	addString(fs, &i.value, i.name, "IP address for the "+object, false)
+}
+
+func (l *List) Add(fs *pflag.FlagSet, object string) {
+	l.name = flags.List
+	addBool(fs, &l.value, l.name, "list the "+object)
+}
+
+func (l *Live) Add(fs *pflag.FlagSet) {
+	l.name = flags.Live
+	addBool(fs, &l.value, l.name, "also resolve references against the live schema")
+}
+
 func (l *Location) Add(fs *pflag.FlagSet, object string) {
 	l.name = flags.Location
 	addString(fs, &l.value, l.name, "location for the "+object, false)
 }
 
+func (m *MAC) Add(fs *pflag.FlagSet, object string) {
+	m.name = flags.MAC
+	addString(fs, &m.value, m.name, "MAC address for the "+object, false)
+}
+
 func (m *Make) Add(fs *pflag.FlagSet, object string, req bool) {
 	m.name = flags.Make
 	addString(fs, &m.value, m.name, "make for the "+object, req)
 }
 
+func (m *Merge) Add(fs *pflag.FlagSet, object string) {
+	m.name = flags.Merge
+	addString(fs, &m.value, m.name, "YAML file to update with the "+object+", keeping its comments", false)
+}
+
 func (m *Model) Add(fs *pflag.FlagSet, object string, req bool) {
 	m.name = flags.Model
 	addString(fs, &m.value, m.name, "model for the "+object, req)
 }
 
+func (m *MTU) Add(fs *pflag.FlagSet, object string) {
+	m.name = flags.MTU
+	addUint32(fs, &m.value, m.name, "MTU for the "+object, false)
+}
+
+func (n *Network) Add(fs *pflag.FlagSet, object string, req bool) {
+	n.name = flags.Network
+	addString(fs, &n.value, n.name, "network for the "+object, req)
+}
+
+func (o *Output) Add(fs *pflag.FlagSet) {
+	o.name = flags.Output
+	fs.StringVarP(&o.value, o.name, "o", "table", "output format: table|wide|json|yaml|csv|tsv|name|go-template=...")
+	fs.StringSliceVar(&o.columns, flags.Columns, nil, "columns to show, in order")
+	fs.StringSliceVar(&o.sortBy, flags.SortBy, nil, "columns to sort by, numbers sort numerically")
+	addBool(fs, &o.noHeaders, flags.NoHeaders, "omit the header line")
+	addBool(fs, &o.reverse, flags.Reverse, "reverse the sort order")
+}
+
+func (o Output) Columns() []string {
+	return o.columns
+}
+
+func (o Output) SortBy() []string {
+	return o.sortBy
+}
+
+func (o Output) NoHeaders() bool {
+	return o.noHeaders
+}
+
+func (o Output) Reverse() bool {
+	return o.reverse
+}
+
+func (p *PassCommand) Add(fs *pflag.FlagSet, object string) {
+	p.name = flags.PassCommand
+	addString(fs, &p.value, p.name, "command that prints the password for the "+object, false)
+}
+
+func (p *PassEnv) Add(fs *pflag.FlagSet, object string) {
+	p.name = flags.PassEnv
+	addString(fs, &p.value, p.name, "environment variable holding the password for the "+object, false)
+}
+
+func (p *PassStdin) Add(fs *pflag.FlagSet) {
+	p.name = flags.PassStdin
+	addBool(fs, &p.value, p.name, "read the password from stdin")
+}
+
+func (p *Prune) Add(fs *pflag.FlagSet, object string) {
+	p.name = flags.Prune
+	addBool(fs, &p.value, p.name, "remove "+object+" missing from the file")
+}
+
 func (r *Rack) Add(fs *pflag.FlagSet, object string, req bool) {
 	r.name = flags.Rack
 	addString(fs, &r.value, r.name, "rack for the "+object, req)
@@ -127,6 +268,16 @@ func (s *Slot) Add(fs *pflag.FlagSet, object string) {
 	addUint32(fs, &s.value, s.name, "slot for the "+object, false)
 }
 
+func (s *Split) Add(fs *pflag.FlagSet, object string) {
+	s.name = flags.Split
+	addString(fs, &s.value, s.name, "directory to write the "+object+" into, a file per zone and cluster", false)
+}
+
+func (s *Subnet) Add(fs *pflag.FlagSet, object string) {
+	s.name = flags.Subnet
+	addString(fs, &s.value, s.name, "subnet for the "+object+" in CIDR notation", false)
+}
+
 func (r *Rename) Add(fs *pflag.FlagSet, object string) {
 	r.name = flags.Rename
 	addString(fs, &r.value, r.name, "rename the "+object, false)
@@ -147,16 +298,19 @@ func (v *Value) Add(fs *pflag.FlagSet, object string) {
 	addString(fs, &v.value, v.name, "value of the "+object, false)
 }
 
-func (z *Zone) Add(fs *pflag.FlagSet, object string, req bool) {
-	z.name = flags.Zone
-
-	def := os.Getenv("METAL_ZONE")
-	fs.StringVar(&z.value, z.name, def, "zone for the "+object)
+func (v *VLAN) Add(fs *pflag.FlagSet, object string) {
+	v.name = flags.VLAN
+	addUint32(fs, &v.value, v.name, "VLAN ID for the "+object, false)
+}
 
-	if req && def == "" {
-		must(cobra.MarkFlagRequired(fs, z.name))
-	}
+func (y *Yes) Add(fs *pflag.FlagSet) {
+	y.name = flags.Yes
+	addBool(fs, &y.value, y.name, "do not ask for confirmation")
+}
 
+func (z *Zone) Add(fs *pflag.FlagSet, object string, req bool) {
+	z.name = flags.Zone
+	addString(fs, &z.value, z.name, "zone for the "+object, req)
 }
 
 func addString(fs *pflag.FlagSet, store *string, name, usage string, req bool) {
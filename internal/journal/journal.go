@@ -0,0 +1,188 @@
+// Package journal keeps the objects that commands changed or removed, so the
+// changes can be undone.
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"endobit.io/stack/internal/diff"
+)
+
+// Entry is one command's change. Before holds the objects named by Names in
+// the List under Parent, as a schema, as they were before the command ran.
+// Rename is the new name of the object if the command renamed it.
+type Entry struct {
+	ID      int             `json:"id"`
+	Time    time.Time       `json:"time"`
+	Server  string          `json:"server"`
+	User    string          `json:"user"`
+	Command []string        `json:"command"`
+	Parent  diff.Path       `json:"parent,omitempty"`
+	List    string          `json:"list"`
+	Names   []string        `json:"names"`
+	Rename  string          `json:"rename,omitempty"`
+	Before  json.RawMessage `json:"before"`
+}
+
+// Store is a directory of entries, one JSON file each.
+type Store struct {
+	dir string
+}
+
+const (
+	ext  = ".json"
+	keep = 100 // entries, older ones are dropped
+)
+
+var errNotFound = errors.New("journal entry not found")
+
+// DefaultDir returns the journal directory, honoring XDG_DATA_HOME.
+func DefaultDir() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+
+		dir = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dir, "stack", "journal")
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Add saves e with the next ID and the current time, and drops the oldest
+// entries beyond the most the journal keeps.
+func (st *Store) Add(e *Entry) error {
+	ids, err := st.ids()
+	if err != nil {
+		return err
+	}
+
+	e.ID = 1
+	if len(ids) > 0 {
+		e.ID = ids[len(ids)-1] + 1
+	}
+
+	e.Time = time.Now().UTC()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(st.dir, 0o700); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(st.file(e.ID), data, 0o600); err != nil {
+		return err
+	}
+
+	for len(ids) >= keep {
+		if err := st.Delete(ids[0]); err != nil {
+			return err
+		}
+
+		ids = ids[1:]
+	}
+
+	return nil
+}
+
+// List returns the entries, oldest first.
+func (st *Store) List() ([]Entry, error) {
+	ids, err := st.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Entry, 0, len(ids))
+
+	for _, id := range ids {
+		e, err := st.Get(id)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, e)
+	}
+
+	return list, nil
+}
+
+func (st *Store) Get(id int) (Entry, error) {
+	var e Entry
+
+	data, err := os.ReadFile(st.file(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return e, fmt.Errorf("%w: %d", errNotFound, id)
+		}
+
+		return e, err
+	}
+
+	if err := json.Unmarshal(data, &e); err != nil {
+		return e, fmt.Errorf("%s: %w", st.file(id), err)
+	}
+
+	return e, nil
+}
+
+// Last returns the newest entry for the server.
+func (st *Store) Last(server string) (Entry, error) {
+	list, err := st.List()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Server == server {
+			return list[i], nil
+		}
+	}
+
+	return Entry{}, fmt.Errorf("%w for %s", errNotFound, server)
+}
+
+func (st *Store) Delete(id int) error {
+	return os.Remove(st.file(id))
+}
+
+func (st *Store) file(id int) string {
+	return filepath.Join(st.dir, strconv.Itoa(id)+ext)
+}
+
+// ids returns the IDs of the entries in order.
+func (st *Store) ids() ([]int, error) {
+	files, err := filepath.Glob(filepath.Join(st.dir, "*"+ext))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(files))
+
+	for _, file := range files {
+		id, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(file), ext))
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	slices.Sort(ids)
+
+	return ids, nil
+}
@@ -0,0 +1,470 @@
+// Package output renders the rows produced by list commands in the format
+// selected with --output.
+package output
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+
+	"endobit.io/table"
+)
+
+// Formats lists the accepted --output values. go-template takes its template
+// after an equals sign.
+var Formats = []string{"table", "wide", "json", "yaml", "csv", "tsv", "name", "go-template=..."}
+
+const goTemplate = "go-template="
+
+var (
+	errFormat = errors.New("unknown output format")
+	errColumn = errors.New("unknown column")
+)
+
+// Options control how rows are rendered.
+type Options struct {
+	Format    string
+	Columns   []string // subset and order of columns, all if empty
+	SortBy    []string // sort keys, compared numerically when both values are numbers
+	NoHeaders bool
+	Reverse   bool
+}
+
+// Writer accepts rows, which must be structs of string fields, and renders
+// them on Flush.
+type Writer interface {
+	Write(row any) error
+	Flush() error
+}
+
+type column struct {
+	field     int
+	name      string
+	header    string
+	key       string
+	omitempty bool
+}
+
+// buffer collects the rows so they can be sorted and have their columns
+// selected before being emitted in the requested format.
+type buffer struct {
+	opts Options
+	w    io.Writer
+	rows []reflect.Value
+	all  []column // every column of the row type
+	cols []column // the columns to emit
+	keys []column // the columns to sort by
+	emit func(*buffer) error
+}
+
+// New returns a Writer that renders to stdout rows of the same type as row,
+// whose columns are printed even when there are no rows. The name column is
+// the one printed by the name format, matched case-insensitively against the
+// row's field names.
+func New(opts Options, name string, row any) (Writer, error) {
+	b := buffer{opts: opts, w: os.Stdout}
+
+	b.all = columns(reflect.Indirect(reflect.ValueOf(row)).Type())
+	b.cols = b.all
+
+	// unknown column names fail here, before any rows are read
+	if err := b.selectColumns(); err != nil {
+		return nil, err
+	}
+
+	for _, key := range opts.SortBy {
+		c, err := b.lookup(key)
+		if err != nil {
+			return nil, fmt.Errorf("sort by %w", err)
+		}
+
+		b.keys = append(b.keys, c)
+	}
+
+	switch opts.Format {
+	case "", "table":
+		b.emit = func(b *buffer) error { return b.table(false) }
+	case "wide":
+		b.emit = func(b *buffer) error { return b.table(true) }
+	case "json":
+		b.emit = (*buffer).json
+	case "yaml":
+		b.emit = (*buffer).yaml
+	case "csv":
+		b.emit = func(b *buffer) error { return b.csv(',') }
+	case "tsv":
+		b.emit = func(b *buffer) error { return b.csv('\t') }
+	case "name":
+		c, err := b.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		b.emit = func(b *buffer) error { return b.name(c) }
+	default:
+		text, ok := strings.CutPrefix(opts.Format, goTemplate)
+		if !ok {
+			return nil, fmt.Errorf("%w %q, must be one of %s", errFormat, opts.Format, strings.Join(Formats, ", "))
+		}
+
+		tmpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return nil, err
+		}
+
+		b.emit = func(b *buffer) error { return b.template(tmpl) }
+	}
+
+	return &b, nil
+}
+
+func (b *buffer) Write(row any) error {
+	b.rows = append(b.rows, reflect.Indirect(reflect.ValueOf(row)))
+
+	return nil
+}
+
+// Flush renders the rows. With none, json and yaml print an empty list and
+// csv and tsv just the header, so scripts always get a document.
+func (b *buffer) Flush() error {
+	b.sort()
+
+	return b.emit(b)
+}
+
+// sort orders the rows by the sort keys, comparing values numerically when
+// both are numbers so that rank 10 follows rank 9.
+func (b *buffer) sort() {
+	slices.SortStableFunc(b.rows, func(x, y reflect.Value) int {
+		for _, c := range b.keys {
+			if n := compare(x.Field(c.field).String(), y.Field(c.field).String()); n != 0 {
+				return n
+			}
+		}
+
+		return 0
+	})
+
+	if b.opts.Reverse {
+		slices.Reverse(b.rows)
+	}
+}
+
+// selectColumns narrows b.cols to the requested columns in the requested
+// order.
+func (b *buffer) selectColumns() error {
+	if len(b.opts.Columns) == 0 {
+		return nil
+	}
+
+	cols := make([]column, 0, len(b.opts.Columns))
+
+	for _, name := range b.opts.Columns {
+		c, err := b.lookup(name)
+		if err != nil {
+			return err
+		}
+
+		c.omitempty = false // asked for explicitly
+		cols = append(cols, c)
+	}
+
+	b.cols = cols
+
+	return nil
+}
+
+func (b *buffer) lookup(name string) (column, error) {
+	for _, c := range b.all {
+		if strings.EqualFold(c.header, name) || c.key == name {
+			return c, nil
+		}
+	}
+
+	keys := make([]string, len(b.all))
+	for i, c := range b.all {
+		keys[i] = c.key
+	}
+
+	return column{}, fmt.Errorf("%w %q, must be one of %s", errColumn, name, strings.Join(keys, ", "))
+}
+
+// empty reports whether column c is blank in every row.
+func (b *buffer) empty(c column) bool {
+	for _, row := range b.rows {
+		if row.Field(c.field).String() != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// table writes the selected columns through endobit.io/table by building a
+// struct type with just those fields. The wide format drops omitempty so
+// every column is shown.
+func (b *buffer) table(wide bool) error {
+	if len(b.rows) == 0 {
+		return nil
+	}
+
+	if b.opts.NoHeaders {
+		return b.tabs(wide)
+	}
+
+	fields := make([]reflect.StructField, len(b.cols))
+
+	for i, c := range b.cols {
+		tag := c.header
+		if c.header == c.name {
+			tag = ""
+		}
+
+		if c.omitempty && !wide {
+			tag += ",omitempty"
+		}
+
+		fields[i] = reflect.StructField{
+			Name: c.name,
+			Type: reflect.TypeFor[string](),
+		}
+
+		if tag != "" {
+			fields[i].Tag = reflect.StructTag(`table:"` + tag + `"`)
+		}
+	}
+
+	typ := reflect.StructOf(fields)
+	t := table.New()
+
+	for _, row := range b.rows {
+		v := reflect.New(typ).Elem()
+
+		for i, c := range b.cols {
+			v.Field(i).SetString(row.Field(c.field).String())
+		}
+
+		if err := t.Write(v.Interface()); err != nil {
+			return err
+		}
+	}
+
+	t.Flush()
+
+	return nil
+}
+
+// tabs writes an aligned table without a header line.
+func (b *buffer) tabs(wide bool) error {
+	var cols []column
+
+	for _, c := range b.cols {
+		if wide || !c.omitempty || !b.empty(c) {
+			cols = append(cols, c)
+		}
+	}
+
+	w := tabwriter.NewWriter(b.w, 0, 8, 2, ' ', 0)
+	vals := make([]string, len(cols))
+
+	for _, row := range b.rows {
+		for i, c := range cols {
+			vals[i] = row.Field(c.field).String()
+		}
+
+		fmt.Fprintln(w, strings.Join(vals, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func (b *buffer) json() error {
+	var buf bytes.Buffer
+
+	buf.WriteByte('[')
+
+	for i, row := range b.rows {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteByte('{')
+
+		for j, c := range b.cols {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+
+			key, _ := json.Marshal(c.key)
+			val, _ := json.Marshal(row.Field(c.field).String())
+
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(val)
+		}
+
+		buf.WriteByte('}')
+	}
+
+	buf.WriteByte(']')
+
+	var out bytes.Buffer
+
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+
+	out.WriteByte('\n')
+
+	_, err := out.WriteTo(b.w)
+
+	return err
+}
+
+func (b *buffer) yaml() error {
+	docs := make([]yaml.MapSlice, 0, len(b.rows))
+
+	for _, row := range b.rows {
+		doc := make(yaml.MapSlice, 0, len(b.cols))
+
+		for _, c := range b.cols {
+			doc = append(doc, yaml.MapItem{Key: c.key, Value: row.Field(c.field).String()})
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return yaml.NewEncoder(b.w).Encode(docs)
+}
+
+func (b *buffer) csv(comma rune) error {
+	w := csv.NewWriter(b.w)
+	w.Comma = comma
+
+	record := make([]string, len(b.cols))
+
+	if !b.opts.NoHeaders {
+		for i, c := range b.cols {
+			record[i] = c.key
+		}
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range b.rows {
+		for i, c := range b.cols {
+			record[i] = row.Field(c.field).String()
+		}
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+func (b *buffer) name(c column) error {
+	for _, row := range b.rows {
+		if _, err := fmt.Fprintln(b.w, row.Field(c.field).String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *buffer) template(tmpl *template.Template) error {
+	for _, row := range b.rows {
+		if err := tmpl.Execute(b.w, row.Interface()); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(b.w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// columns describes the exported fields of a row type, honoring the header
+// name and omitempty option of the table struct tag.
+func columns(t reflect.Type) []column {
+	var cols []column
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		header, opts, _ := strings.Cut(f.Tag.Get("table"), ",")
+		if header == "" {
+			header = f.Name
+		}
+
+		cols = append(cols, column{
+			field:     i,
+			name:      f.Name,
+			header:    header,
+			key:       snake(header),
+			omitempty: opts == "omitempty",
+		})
+	}
+
+	return cols
+}
+
+// compare orders a and b numerically if both are integers and lexically
+// otherwise.
+func compare(a, b string) int {
+	x, errX := strconv.Atoi(a)
+	y, errY := strconv.Atoi(b)
+
+	if errX == nil && errY == nil {
+		return cmp.Compare(x, y)
+	}
+
+	return strings.Compare(a, b)
+}
+
+// snake converts a Go field name such as TimeZone to time_zone.
+func snake(s string) string {
+	var b strings.Builder
+
+	runes := []rune(s)
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,205 @@
+package output
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+type row struct {
+	Zone     string
+	Host     string
+	Rank     string `table:",omitempty"`
+	TimeZone string `table:",omitempty"`
+}
+
+var rows = []row{
+	{Zone: "z1", Host: "h10", Rank: "10"},
+	{Zone: "z1", Host: "h9", Rank: "9"},
+	{Zone: "z2", Host: "h1", Rank: "1", TimeZone: `America/"Los,Angeles"`},
+}
+
+// render writes rows through a Writer made with opts and returns what it
+// prints.
+func render[T any](t *testing.T, opts Options, rows []T) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	var zero T
+
+	tw, err := New(opts, "host", zero)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, row := range rows {
+		if err := tw.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	w.Close()
+
+	return <-out
+}
+
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != string(want) {
+		t.Errorf("%s: got\n%s\nwant\n%s", name, got, want)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "json", opts: Options{Format: "json"}},
+		{name: "yaml", opts: Options{Format: "yaml"}},
+		{name: "csv", opts: Options{Format: "csv"}},
+		{name: "tsv", opts: Options{Format: "tsv"}},
+		{name: "name", opts: Options{Format: "name"}},
+		{name: "go-template", opts: Options{Format: "go-template={{.Zone}}/{{.Host}}"}},
+		{name: "no-headers", opts: Options{Format: "table", NoHeaders: true}},
+	}
+
+	for _, tt := range tests {
+		golden(t, tt.name, render(t, tt.opts, rows))
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "table"},
+		{format: "json", want: "[]\n"},
+		{format: "yaml", want: "[]\n"},
+		{format: "csv", want: "zone,host,rank,time_zone\n"},
+		{format: "tsv", want: "zone\thost\trank\ttime_zone\n"},
+		{format: "name"},
+	}
+
+	for _, tt := range tests {
+		if got := render[row](t, Options{Format: tt.format}, nil); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+// TestTable checks the table formats by their cells, as the spacing is up to
+// endobit.io/table. Table leaves out the blank omitempty column, wide keeps
+// it.
+func TestTable(t *testing.T) {
+	type host struct {
+		Zone string
+		Host string
+		Rank string `table:",omitempty"`
+	}
+
+	rows := []host{
+		{Zone: "z1", Host: "h1"},
+		{Zone: "z1", Host: "h2"},
+	}
+
+	tests := []struct {
+		format string
+		header []string
+	}{
+		{format: "table", header: []string{"zone", "host"}},
+		{format: "wide", header: []string{"zone", "host", "rank"}},
+	}
+
+	for _, tt := range tests {
+		lines := strings.Split(strings.TrimSpace(render(t, Options{Format: tt.format}, rows)), "\n")
+		want := []string{strings.Join(tt.header, " "), "z1 h1", "z1 h2"}
+
+		if len(lines) != len(want) {
+			t.Fatalf("%s: got %d lines, want %d", tt.format, len(lines), len(want))
+		}
+
+		for i := range want {
+			got := strings.Join(strings.Fields(lines[i]), " ")
+			if !strings.EqualFold(got, want[i]) {
+				t.Errorf("%s line %d: got %q, want %q", tt.format, i, got, want[i])
+			}
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{opts: Options{SortBy: []string{"rank"}}, want: "h1\nh9\nh10\n"},
+		{opts: Options{SortBy: []string{"Rank"}, Reverse: true}, want: "h10\nh9\nh1\n"},
+		{opts: Options{SortBy: []string{"zone", "host"}}, want: "h10\nh9\nh1\n"},
+		{opts: Options{Format: "csv", Columns: []string{"host", "ZONE"}}, want: "host,zone\nh10,z1\nh9,z1\nh1,z2\n"},
+		{opts: Options{Format: "csv", Columns: []string{"time_zone"}, NoHeaders: true}, want: "\n\n\"America/\"\"Los,Angeles\"\"\"\n"},
+	}
+
+	for _, tt := range tests {
+		if tt.opts.Format == "" {
+			tt.opts.Format = "name"
+		}
+
+		if got := render(t, tt.opts, rows); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownColumn(t *testing.T) {
+	for _, opts := range []Options{
+		{Columns: []string{"host", "rack"}},
+		{SortBy: []string{"rack"}},
+		{Format: "name"},
+	} {
+		if _, err := New(opts, "rack", row{}); !errors.Is(err, errColumn) {
+			t.Errorf("%+v: got %v, want %v", opts, err, errColumn)
+		}
+	}
+}
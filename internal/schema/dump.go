@@ -0,0 +1,201 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// Marshal renders doc in the given format with its fields in proto order and
+// its named objects sorted by name, so dumps of the same schema are
+// identical. doc is not modified.
+func Marshal(doc *pb.Schema, format string) ([]byte, error) {
+	doc = proto.CloneOf(doc)
+	Sort(doc.ProtoReflect())
+
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case JSON:
+		var out bytes.Buffer
+
+		// protojson varies its whitespace, so indent it ourselves
+		if err := json.Indent(&out, b, "", "  "); err != nil {
+			return nil, err
+		}
+
+		out.WriteByte('\n')
+
+		return out.Bytes(), nil
+	case YAML:
+		var obj yaml.MapSlice
+
+		if err := yaml.UnmarshalWithOptions(b, &obj, yaml.UseOrderedMap()); err != nil {
+			return nil, err
+		}
+
+		return yaml.MarshalWithOptions(obj, yaml.IndentSequence(true))
+	}
+
+	return nil, fmt.Errorf("%w %q", errFormat, format)
+}
+
+// Sort orders every list of named objects in m by name, recursively.
+func Sort(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && named(fd):
+			l := v.List()
+			elems := make([]protoreflect.Value, l.Len())
+
+			for i := range l.Len() {
+				elems[i] = l.Get(i)
+				Sort(elems[i].Message())
+			}
+
+			slices.SortStableFunc(elems, func(a, b protoreflect.Value) int {
+				return strings.Compare(name(a.Message()), name(b.Message()))
+			})
+
+			for i, e := range elems {
+				l.Set(i, e)
+			}
+		case fd.Message() != nil && !fd.IsList() && !fd.IsMap():
+			Sort(v.Message())
+		}
+
+		return true
+	})
+}
+
+// MergeComments returns the YAML document fresh with the comments from old
+// carried over. Mapping entries are matched by key and list elements by
+// name, so comments follow their objects even when the objects move.
+func MergeComments(old, fresh []byte) ([]byte, error) {
+	from, err := parser.ParseBytes(old, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parser.ParseBytes(fresh, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, doc := range to.Docs {
+		if i < len(from.Docs) && doc.Body != nil && from.Docs[i].Body != nil {
+			copyComments(from.Docs[i].Body, doc.Body)
+		}
+	}
+
+	return []byte(strings.TrimRight(to.String(), "\n") + "\n"), nil
+}
+
+func copyComments(from, to ast.Node) {
+	fromSeq, ok := unwrap(from).(*ast.SequenceNode)
+	if ok {
+		if toSeq, ok := unwrap(to).(*ast.SequenceNode); ok {
+			copySeqComments(fromSeq, toSeq)
+		}
+
+		return
+	}
+
+	if c := from.GetComment(); c != nil {
+		_ = to.SetComment(c)
+	}
+
+	fromPairs, ok := mapping(from)
+	if !ok {
+		return
+	}
+
+	toPairs, ok := mapping(to)
+	if !ok {
+		return
+	}
+
+	for _, p := range toPairs {
+		for _, q := range fromPairs {
+			if text(q.Key) != text(p.Key) {
+				continue
+			}
+
+			if c := q.GetComment(); c != nil {
+				_ = p.SetComment(c)
+			}
+
+			if q.FootComment != nil {
+				p.FootComment = q.FootComment
+			}
+
+			copyComments(q.Value, p.Value)
+
+			break
+		}
+	}
+}
+
+// copySeqComments matches list elements by name, or by position if they have
+// none. The parser keeps the first element's head comment as the comment of
+// the list itself.
+func copySeqComments(from, to *ast.SequenceNode) {
+	head := func(i int) *ast.CommentGroupNode {
+		if i == 0 {
+			return from.GetComment()
+		}
+
+		if i < len(from.ValueHeadComments) {
+			return from.ValueHeadComments[i]
+		}
+
+		return nil
+	}
+
+	for i, el := range to.Values {
+		j := slices.IndexFunc(from.Values, func(n ast.Node) bool {
+			key := nameOf(n)
+			return key != "" && key == nameOf(el)
+		})
+
+		if j < 0 && i < len(from.Values) && nameOf(el) == "" {
+			j = i
+		}
+
+		if j < 0 {
+			continue
+		}
+
+		if c := head(j); c != nil {
+			if i == 0 {
+				_ = to.SetComment(c)
+			} else {
+				if len(to.ValueHeadComments) != len(to.Values) { // printed only if one per value
+					to.ValueHeadComments = make([]*ast.CommentGroupNode, len(to.Values))
+				}
+
+				to.ValueHeadComments[i] = c
+			}
+		}
+
+		copyComments(from.Values[j], el)
+	}
+
+	if from.FootComment != nil {
+		to.FootComment = from.FootComment
+	}
+}
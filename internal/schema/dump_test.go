@@ -0,0 +1,61 @@
+package schema
+
+import "testing"
+
+func TestMergeComments(t *testing.T) {
+	old := `# managed by stack dump
+zones:
+  # first zone
+  - name: z1 # inline z1
+    time_zone: UTC # keep UTC
+  # second zone
+  - name: z2
+    clusters:
+      # cluster c1
+      - name: c1
+`
+
+	// z1 and z2 swapped, with z0 and c0 added ahead of them
+	fresh := `zones:
+  - name: z0
+  - name: z2
+    clusters:
+      - name: c0
+      - name: c1
+  - name: z1
+    time_zone: UTC
+`
+
+	want := `# managed by stack dump
+zones:
+  - name: z0
+  # second zone
+  - name: z2
+    clusters:
+      - name: c0
+      # cluster c1
+      - name: c1
+  # first zone
+  - name: z1 # inline z1
+    time_zone: UTC # keep UTC
+`
+
+	got, err := MergeComments([]byte(old), []byte(fresh))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// dumping again over the merged file keeps them
+	got, err = MergeComments(got, []byte(fresh))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != want {
+		t.Errorf("second dump: got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package schema
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+const draft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema describes the document that dump writes and load reads, as a
+// JSON Schema derived from the pb.Schema descriptor. Properties use the proto
+// field names, as dump does, as well as the JSON names load also accepts, and
+// enums take their names or numbers. Messages are shared through $defs.
+func JSONSchema() map[string]any {
+	defs := make(map[string]any)
+	md := (&pb.Schema{}).ProtoReflect().Descriptor()
+
+	root := message(md, defs)
+	root["$schema"] = draft
+	root["title"] = "stack schema"
+	root["$defs"] = defs
+
+	return root
+}
+
+func message(md protoreflect.MessageDescriptor, defs map[string]any) map[string]any {
+	props := make(map[string]any)
+	fields := md.Fields()
+
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		props[string(fd.Name())] = field(fd, defs)
+		props[fd.JSONName()] = props[string(fd.Name())]
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+func field(fd protoreflect.FieldDescriptor, defs map[string]any) map[string]any {
+	switch {
+	case fd.IsMap():
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": value(fd.MapValue(), defs),
+		}
+	case fd.IsList():
+		return map[string]any{
+			"type":  "array",
+			"items": value(fd, defs),
+		}
+	}
+
+	return value(fd, defs)
+}
+
+// value describes a single value of fd, ignoring whether it is repeated.
+func value(fd protoreflect.FieldDescriptor, defs map[string]any) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]any{"type": "number"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson writes 64-bit integers as strings
+		return map[string]any{"type": []string{"integer", "string"}}
+	case protoreflect.EnumKind:
+		return map[string]any{"anyOf": []any{
+			map[string]any{"enum": enumNames(fd.Enum())},
+			map[string]any{"type": "integer"},
+		}}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		md := fd.Message()
+		if wellKnown(md) {
+			return map[string]any{}
+		}
+
+		name := string(md.FullName())
+
+		if _, ok := defs[name]; !ok {
+			defs[name] = nil // reserve the name so recursive messages stop here
+			defs[name] = message(md, defs)
+		}
+
+		return map[string]any{"$ref": "#/$defs/" + name}
+	}
+
+	return map[string]any{}
+}
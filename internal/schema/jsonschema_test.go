@@ -0,0 +1,140 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// fill sets every field of m, and of the messages in it down to depth, so that
+// a dump of m has every property the JSON Schema describes.
+func fill(m protoreflect.Message, depth int) {
+	fields := m.Descriptor().Fields()
+
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				m.Mutable(fd).Map().Set(sample(fd.MapKey()).MapKey(), sample(fd.MapValue()))
+			}
+		case fd.Message() != nil:
+			if depth == 0 || wellKnown(fd.Message()) {
+				continue
+			}
+
+			if !fd.IsList() {
+				fill(m.Mutable(fd).Message(), depth-1)
+				continue
+			}
+
+			l := m.Mutable(fd).List()
+			el := l.NewElement()
+			fill(el.Message(), depth-1)
+			l.Append(el)
+		case fd.IsList():
+			m.Mutable(fd).List().Append(sample(fd))
+		default:
+			m.Set(fd, sample(fd))
+		}
+	}
+}
+
+func sample(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString("x")
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte("x"))
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		return protoreflect.ValueOfEnum(values.Get(values.Len() - 1).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(-1)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(-1)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(1)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(1)
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(1.5)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(1.5)
+	}
+
+	return protoreflect.Value{}
+}
+
+func TestJSONSchema(t *testing.T) {
+	b, err := json.Marshal(JSONSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := jsonschema.NewCompiler()
+
+	if err := c.AddResource("stack.json", bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+
+	sch, err := c.Compile("stack.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc pb.Schema
+
+	fill(doc.ProtoReflect(), 5)
+
+	dump, err := Marshal(&doc, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	camel, err := protojson.Marshal(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	numbers, err := protojson.MarshalOptions{UseProtoNames: true, UseEnumNumbers: true}.Marshal(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		valid bool
+	}{
+		{name: "dump", data: dump, valid: true},
+		{name: "json names", data: camel, valid: true},
+		{name: "enum numbers", data: numbers, valid: true},
+		{name: "unknown field", data: []byte(`{"no_such_field": []}`)},
+	}
+
+	for _, tt := range tests {
+		var v any
+
+		if err := json.Unmarshal(tt.data, &v); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if err := sch.Validate(v); (err == nil) != tt.valid {
+			t.Errorf("%s: got %v, want valid %t", tt.name, err, tt.valid)
+		}
+
+		if _, err := Parse(tt.data, JSON); (err == nil) != tt.valid {
+			t.Errorf("%s: load got %v, want valid %t", tt.name, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package schema reads stack schema files.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// Stdin is the file name that reads from standard input.
+const Stdin = "-"
+
+const (
+	JSON = "json"
+	YAML = "yaml"
+)
+
+var errFormat = errors.New("unknown file format")
+
+// Source is the content of one schema file.
+type Source struct {
+	Name   string
+	Format string
+	Data   []byte
+}
+
+// Read parses and merges the named files into one schema.
+func Read(names []string, format string) (*pb.Schema, error) {
+	srcs, err := Open(names, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return Merge(srcs)
+}
+
+// Open reads the named files. Directories are walked for .json, .yaml and
+// .yml files. The format applies to every file if set, otherwise it comes
+// from the extension or, failing that, the content.
+func Open(names []string, format string) ([]Source, error) {
+	var srcs []Source
+
+	for _, name := range names {
+		files, err := expand(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			src, err := readFile(file, format)
+			if err != nil {
+				return nil, err
+			}
+
+			srcs = append(srcs, src)
+		}
+	}
+
+	return srcs, nil
+}
+
+// Merge parses the sources into one schema.
+func Merge(srcs []Source) (*pb.Schema, error) {
+	var doc pb.Schema
+
+	for _, src := range srcs {
+		part, err := Parse(src.Data, src.Format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src.Name, err)
+		}
+
+		merge(doc.ProtoReflect(), part.ProtoReflect())
+	}
+
+	return &doc, nil
+}
+
+// expand returns the schema files under name if it is a directory, or name
+// itself.
+func expand(name string) ([]string, error) {
+	if name == Stdin {
+		return []string{name}, nil
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{name}, nil
+	}
+
+	var files []string
+
+	err = filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && formatOf(path) != "" {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+func readFile(name, format string) (Source, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if name == Stdin {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+
+	if err != nil {
+		return Source{}, err
+	}
+
+	if format == "" {
+		format = formatOf(name)
+	}
+
+	if format == "" {
+		format = sniff(data)
+	}
+
+	return Source{Name: name, Format: format, Data: data}, nil
+}
+
+// Parse decodes a schema in the given format.
+func Parse(data []byte, format string) (*pb.Schema, error) {
+	var doc pb.Schema
+
+	switch format {
+	case JSON:
+		if err := protojson.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	case YAML:
+		var jsonMap map[string]any
+
+		if err := yaml.Unmarshal(data, &jsonMap); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
+		}
+
+		jsonData, err := json.Marshal(jsonMap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal json: %w", err)
+		}
+
+		if err := protojson.Unmarshal(jsonData, &doc); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal json: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", errFormat, format)
+	}
+
+	return &doc, nil
+}
+
+func formatOf(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return JSON
+	case ".yaml", ".yml":
+		return YAML
+	}
+
+	return ""
+}
+
+// sniff guesses the format from the content. JSON documents are objects, so
+// anything else is taken to be YAML.
+func sniff(data []byte) string {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return JSON
+	}
+
+	return YAML
+}
+
+// merge adds src to dst. Lists of named objects are merged by name, so a zone
+// spread over several files ends up as one zone. Other fields set in src
+// replace those in dst.
+func merge(dst, src protoreflect.Message) {
+	src.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && named(fd):
+			mergeList(dst.Mutable(fd).List(), v.List())
+		case fd.IsList():
+			l := dst.Mutable(fd).List()
+			for i := range v.List().Len() {
+				l.Append(v.List().Get(i))
+			}
+		case fd.Message() != nil && !fd.IsMap():
+			merge(dst.Mutable(fd).Message(), v.Message())
+		default:
+			dst.Set(fd, v)
+		}
+
+		return true
+	})
+}
+
+func mergeList(dst, src protoreflect.List) {
+	for i := range src.Len() {
+		m := src.Get(i).Message()
+
+		if j := find(dst, name(m)); j >= 0 {
+			merge(dst.Get(j).Message(), m)
+		} else {
+			dst.Append(src.Get(i))
+		}
+	}
+}
+
+func find(l protoreflect.List, key string) int {
+	for i := range l.Len() {
+		if name(l.Get(i).Message()) == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func named(fd protoreflect.FieldDescriptor) bool {
+	if fd.Message() == nil {
+		return false
+	}
+
+	key := fd.Message().Fields().ByName("name")
+
+	return key != nil && key.Kind() == protoreflect.StringKind && !key.IsList()
+}
+
+func name(m protoreflect.Message) string {
+	return m.Get(m.Descriptor().Fields().ByName("name")).String()
+}
+
+// Zone returns just the named zone of doc, leaving out everything outside
+// it, and whether doc has the zone.
+func Zone(doc *pb.Schema, zone string) (*pb.Schema, bool) {
+	var out pb.Schema
+
+	fd := doc.ProtoReflect().Descriptor().Fields().ByName("zones")
+	if fd == nil {
+		return &out, false
+	}
+
+	zones := doc.ProtoReflect().Get(fd).List()
+
+	for i := range zones.Len() {
+		if name(zones.Get(i).Message()) == zone {
+			out.ProtoReflect().Mutable(fd).List().Append(zones.Get(i))
+			return &out, true
+		}
+	}
+
+	return &out, false
+}
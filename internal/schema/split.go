@@ -0,0 +1,242 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// splits names the lists whose objects each get a file, and the directory
+// those files go in: zone/<zone>.yaml and zone/<zone>/cluster/<cluster>.yaml.
+var splits = map[string]string{
+	"zones":    "zone",
+	"clusters": "cluster",
+}
+
+// standalone names the lists kept apart from the object holding them, such
+// as the hosts of a zone that are not in any cluster.
+var standalone = map[string]string{
+	"zones": "hosts",
+}
+
+// rootFile holds whatever is left at the top of the schema.
+const rootFile = "schema"
+
+var errSplitName = errors.New("cannot split on name")
+
+// Scope is the part of the schema a dump was read for. Only files within it
+// are removed when a split dump is written.
+type Scope struct {
+	Zone    string
+	Cluster string
+	Host    string
+}
+
+type splitter struct {
+	files map[string]*pb.Schema
+	ext   string
+	err   error
+}
+
+// Split divides doc into one document per file, keyed by the file's path
+// relative to the dump directory. Each document wraps its objects in their
+// parents, named but otherwise empty, so loading the directory merges them
+// back into doc. Names that are not a single path element cannot be split.
+func Split(doc *pb.Schema, format string) (map[string]*pb.Schema, error) {
+	s := splitter{files: make(map[string]*pb.Schema), ext: "." + format}
+
+	s.split(doc.ProtoReflect(), "", "", func(m protoreflect.Message) *pb.Schema {
+		return m.Interface().(*pb.Schema) //nolint:forcetypeassert
+	})
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return s.files, nil
+}
+
+// split files the objects of m under dir. list is the field m was found in,
+// empty for the schema itself, and wrap puts a message of m's type back into
+// m's parents.
+func (s *splitter) split(m protoreflect.Message, list, dir string, wrap func(protoreflect.Message) *pb.Schema) {
+	base := proto.Clone(m.Interface()).ProtoReflect()
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		field := string(fd.Name())
+
+		if !fd.IsList() || !named(fd) {
+			return true
+		}
+
+		switch {
+		case splits[field] != "":
+			base.Clear(fd)
+
+			for i := range v.List().Len() {
+				el := v.List().Get(i).Message()
+
+				if n := name(el); n == "" || strings.ContainsAny(n, `/\`) || strings.Contains(n, "..") {
+					s.err = fmt.Errorf("%w %q in %s", errSplitName, n, field)
+					return false
+				}
+
+				s.split(el, field, filepath.Join(dir, splits[field], name(el)), func(child protoreflect.Message) *pb.Schema {
+					parent := skeleton(m)
+					parent.Mutable(fd).List().Append(protoreflect.ValueOfMessage(child))
+
+					return wrap(parent)
+				})
+			}
+		case list == "" || standalone[list] == field:
+			base.Clear(fd)
+
+			part := skeleton(m)
+			part.Set(fd, v)
+
+			s.files[filepath.Join(dir, field+s.ext)] = wrap(part)
+		}
+
+		return true
+	})
+
+	if list != "" {
+		s.files[dir+s.ext] = wrap(base)
+	} else if hasFields(base) {
+		s.files[rootFile+s.ext] = wrap(base)
+	}
+}
+
+// skeleton returns an empty message of m's type with just m's name.
+func skeleton(m protoreflect.Message) protoreflect.Message {
+	n := m.New()
+
+	if fd := m.Descriptor().Fields().ByName("name"); fd != nil {
+		n.Set(fd, m.Get(fd))
+	}
+
+	return n
+}
+
+func hasFields(m protoreflect.Message) bool {
+	found := false
+
+	m.Range(func(protoreflect.FieldDescriptor, protoreflect.Value) bool {
+		found = true
+		return false
+	})
+
+	return found
+}
+
+// WriteSplit writes the split doc under dir. Schema files that a dump of
+// scope would have written, left in dir by an earlier dump, are removed so
+// that loading dir does not bring back objects that have since been deleted.
+// Nothing else in dir is touched.
+func WriteSplit(dir string, doc *pb.Schema, format string, scope Scope) error {
+	files, err := Split(doc, format)
+	if err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if top, _, _ := strings.Cut(filepath.ToSlash(rel), "/"); rel != "." && top != splits["zones"] {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if _, ok := files[rel]; !ok && scope.owns(rel) {
+			return os.Remove(path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for rel, part := range files {
+		data, err := Marshal(part, format)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, rel)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gosec
+			return err
+		}
+
+		if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec
+			return err
+		}
+	}
+
+	return nil
+}
+
+// owns reports whether rel, relative to the dump directory, is a schema file
+// that a split dump of the scope writes. The top-level files are only owned
+// by a dump of everything, and a host's objects are spread across files that
+// hold other hosts too, so a host dump owns none.
+func (s Scope) owns(rel string) bool {
+	if formatOf(rel) == "" || s.Host != "" {
+		return false
+	}
+
+	parts := strings.Split(filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel))), "/")
+
+	switch {
+	case s.Zone == "" && s.Cluster == "":
+		if len(parts) == 1 {
+			return topLevel()[parts[0]]
+		}
+
+		return parts[0] == splits["zones"]
+	case s.Cluster == "":
+		return len(parts) >= 2 && parts[0] == splits["zones"] && parts[1] == s.Zone
+	default:
+		return len(parts) >= 4 && parts[0] == splits["zones"] && (s.Zone == "" || parts[1] == s.Zone) &&
+			parts[2] == splits["clusters"] && parts[3] == s.Cluster
+	}
+}
+
+// topLevel returns the names of the files split from the top of the schema.
+func topLevel() map[string]bool {
+	names := map[string]bool{rootFile: true}
+	fields := (*pb.Schema)(nil).ProtoReflect().Descriptor().Fields()
+
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+
+		if fd.IsList() && named(fd) && splits[string(fd.Name())] == "" {
+			names[string(fd.Name())] = true
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const splitDoc = `
+makes:
+  - name: m1
+zones:
+  - name: z1
+    clusters:
+      - name: c1
+`
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+func TestWriteSplit(t *testing.T) {
+	doc, err := Parse([]byte(splitDoc), YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept := []string{".golangci.yaml", ".github/workflows/ci.yml", "docs/example.json", "notes.yaml"}
+
+	tests := []struct {
+		scope   Scope
+		removed []string
+		kept    []string
+	}{
+		{
+			scope:   Scope{},
+			removed: []string{"attrs.yaml", "zone/z2.yaml", "zone/z1/cluster/c2.yaml"},
+		},
+		{
+			scope:   Scope{Zone: "z1"},
+			removed: []string{"zone/z1/cluster/c2.yaml", "zone/z1/hosts.yaml"},
+			kept:    []string{"attrs.yaml", "zone/z2.yaml", "zone/z2/cluster/c1.yaml"},
+		},
+		{
+			scope:   Scope{Zone: "z1", Cluster: "c2"},
+			removed: []string{"zone/z1/cluster/c2.yaml"},
+			kept:    []string{"attrs.yaml", "zone/z2.yaml", "zone/z1/hosts.yaml", "zone/z1/cluster/c3.yaml"},
+		},
+		{
+			scope: Scope{Zone: "z1", Host: "h1"},
+			kept:  []string{"attrs.yaml", "zone/z2.yaml", "zone/z1/hosts.yaml", "zone/z1/cluster/c2.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+
+		writeFiles(t, dir, kept...)
+		writeFiles(t, dir, tt.removed...)
+		writeFiles(t, dir, tt.kept...)
+
+		if err := WriteSplit(dir, doc, YAML, tt.scope); err != nil {
+			t.Fatalf("%+v: %v", tt.scope, err)
+		}
+
+		for _, name := range []string{"makes.yaml", "zone/z1.yaml", "zone/z1/cluster/c1.yaml"} {
+			if !exists(dir, name) {
+				t.Errorf("%+v: %s not written", tt.scope, name)
+			}
+		}
+
+		for _, name := range tt.removed {
+			if exists(dir, name) {
+				t.Errorf("%+v: %s not removed", tt.scope, name)
+			}
+		}
+
+		for _, name := range append(kept, tt.kept...) {
+			if !exists(dir, name) {
+				t.Errorf("%+v: %s removed", tt.scope, name)
+			}
+		}
+	}
+}
+
+func TestWriteSplitName(t *testing.T) {
+	for _, name := range []string{"..", "../out", "a/b", `a\b`} {
+		doc, err := Parse([]byte("zones:\n  - name: '"+name+"'\n"), YAML)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dir := filepath.Join(t.TempDir(), "dump")
+
+		if err := WriteSplit(dir, doc, YAML, Scope{}); !errors.Is(err, errSplitName) {
+			t.Errorf("%q: got %v, want %v", name, err, errSplitName)
+		}
+
+		if exists(dir, ".") {
+			t.Errorf("%q: wrote %s", name, dir)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// references maps the fields that name another object to the list holding
+// those objects.
+var references = map[string]string{
+	"appliance":   "appliances",
+	"environment": "environments",
+	"make":        "makes",
+	"model":       "models",
+	"network":     "networks",
+	"rack":        "racks",
+}
+
+// Error is a problem at a position in a schema file.
+type Error struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
+
+// step is one hop from the schema root, as in diff.Path.
+type step struct {
+	field string
+	key   string
+}
+
+// ref is a field naming another object, which must exist in an enclosing
+// scope.
+type ref struct {
+	pos    Error
+	path   []step
+	fields map[string]string // the referring object's string fields
+	list   string
+	name   string
+}
+
+type definition struct {
+	parent []step
+	list   string
+	name   string
+}
+
+type validator struct {
+	file string
+	errs []error
+	refs []ref
+}
+
+// Validate checks the sources against the shape of pb.Schema and checks that
+// the objects they refer to exist in the sources or in live, which may be
+// nil. Every problem is reported with its file, line and column.
+func Validate(srcs []Source, live *pb.Schema) error {
+	var v validator
+
+	md := (&pb.Schema{}).ProtoReflect().Descriptor()
+
+	for _, src := range srcs {
+		v.file = src.Name
+
+		f, err := parser.ParseBytes(src.Data, 0)
+		if err != nil {
+			v.errs = append(v.errs, fmt.Errorf("%s: %w", src.Name, err))
+			continue
+		}
+
+		for _, doc := range f.Docs {
+			if doc.Body != nil {
+				v.message(doc.Body, md, nil)
+			}
+		}
+	}
+
+	if len(v.errs) > 0 { // the sources may not merge
+		return errors.Join(v.errs...)
+	}
+
+	doc, err := Merge(srcs)
+	if err != nil {
+		return err
+	}
+
+	v.resolve(md, doc, live)
+
+	return errors.Join(v.errs...)
+}
+
+func (v *validator) errorf(n ast.Node, format string, args ...any) {
+	v.errs = append(v.errs, position(v.file, n, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) message(n ast.Node, md protoreflect.MessageDescriptor, path []step) {
+	if wellKnown(md) {
+		return // these have their own JSON forms
+	}
+
+	pairs, ok := mapping(n)
+	if !ok {
+		v.errorf(n, "expected a mapping for %s", md.Name())
+		return
+	}
+
+	fields := make(map[string]string)
+
+	var refs []ref
+
+	for _, p := range pairs {
+		key := text(p.Key)
+
+		fd := md.Fields().ByName(protoreflect.Name(key))
+		if fd == nil {
+			fd = md.Fields().ByJSONName(key)
+		}
+
+		if fd == nil {
+			v.errorf(p.Key, "unknown field %q in %s", key, md.Name())
+			continue
+		}
+
+		val := unwrap(p.Value)
+		if _, ok := val.(*ast.NullNode); ok || val == nil {
+			continue
+		}
+
+		field := string(fd.Name())
+
+		switch {
+		case fd.IsMap():
+			entries, ok := mapping(val)
+			if !ok {
+				v.errorf(val, "expected a mapping for %s", field)
+				continue
+			}
+
+			for _, e := range entries {
+				v.value(unwrap(e.Value), fd.MapValue(), path)
+			}
+		case fd.IsList():
+			v.list(val, fd, path)
+		default:
+			v.value(val, fd, path)
+
+			if fd.Kind() == protoreflect.StringKind {
+				fields[field] = text(val)
+
+				if list, ok := references[field]; ok && text(val) != "" {
+					refs = append(refs, ref{pos: position(v.file, val, ""), list: list, name: text(val)})
+				}
+			}
+		}
+	}
+
+	for _, r := range refs {
+		r.path = path
+		r.fields = fields
+		v.refs = append(v.refs, r)
+	}
+}
+
+func (v *validator) list(n ast.Node, fd protoreflect.FieldDescriptor, path []step) {
+	seq, ok := n.(*ast.SequenceNode)
+	if !ok {
+		v.errorf(n, "expected a list for %s", fd.Name())
+		return
+	}
+
+	seen := make(map[string]bool)
+
+	for _, el := range seq.Values {
+		el = unwrap(el)
+
+		if fd.Message() == nil || !named(fd) {
+			v.value(el, fd, path)
+			continue
+		}
+
+		key := nameOf(el)
+		if key == "" {
+			v.errorf(el, "missing name in %s", fd.Name())
+		} else if seen[key] {
+			v.errorf(el, "duplicate name %q in %s", key, fd.Name())
+		}
+
+		seen[key] = true
+
+		v.message(el, fd.Message(), append(path[:len(path):len(path)], step{string(fd.Name()), key}))
+	}
+}
+
+// value checks a single value, which is a message or a scalar of fd's kind.
+func (v *validator) value(n ast.Node, fd protoreflect.FieldDescriptor, path []step) {
+	if fd.Message() != nil {
+		v.message(n, fd.Message(), append(path[:len(path):len(path)], step{field: string(fd.Name())}))
+		return
+	}
+
+	switch n.(type) {
+	case *ast.MappingNode, *ast.MappingValueNode, *ast.SequenceNode:
+		v.errorf(n, "expected a %s for %s", fd.Kind(), fd.Name())
+		return
+	}
+
+	s := text(n)
+
+	var err error
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		_, err = strconv.ParseBool(s)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		_, err = strconv.ParseInt(s, 10, 32)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		_, err = strconv.ParseInt(s, 10, 64)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		_, err = strconv.ParseUint(s, 10, 32)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		_, err = strconv.ParseUint(s, 10, 64)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		_, err = strconv.ParseFloat(s, 64)
+	case protoreflect.EnumKind:
+		if fd.Enum().Values().ByName(protoreflect.Name(s)) == nil {
+			if _, err := strconv.ParseInt(s, 10, 32); err != nil {
+				v.errorf(n, "invalid %s %q, must be one of %s", fd.Name(), s, strings.Join(enumNames(fd.Enum()), ", "))
+			}
+		}
+	}
+
+	if err != nil {
+		v.errorf(n, "invalid %s %q, expected a %s", fd.Name(), s, fd.Kind())
+	}
+}
+
+// resolve reports the references to objects that are in neither doc nor
+// live. Only references to kinds of object the schema has are checked.
+func (v *validator) resolve(md protoreflect.MessageDescriptor, doc, live *pb.Schema) {
+	lists := make(map[string]bool)
+	listsOf(md, lists, make(map[protoreflect.FullName]bool))
+
+	var defs []definition
+
+	defined(doc.ProtoReflect(), nil, &defs)
+
+	if live != nil {
+		defined(live.ProtoReflect(), nil, &defs)
+	}
+
+	for _, r := range v.refs {
+		if !lists[r.list] || r.resolved(defs) {
+			continue
+		}
+
+		e := r.pos
+		e.Msg = fmt.Sprintf("%s %q not found", strings.TrimSuffix(r.list, "s"), r.name)
+		v.errs = append(v.errs, e)
+	}
+}
+
+// resolved reports whether a definition matches the reference. Its parents
+// must enclose the referring object, or be named by one of the object's
+// fields, as a model within a make is named by a host's make.
+func (r ref) resolved(defs []definition) bool {
+next:
+	for _, d := range defs {
+		if d.list != r.list || d.name != r.name {
+			continue
+		}
+
+		for _, p := range d.parent {
+			if !r.encloses(p) {
+				continue next
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (r ref) encloses(p step) bool {
+	for _, s := range r.path {
+		if s == p {
+			return true
+		}
+	}
+
+	for field, list := range references {
+		if list == p.field && r.fields[field] == p.key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// defined collects the named objects in m.
+func defined(m protoreflect.Message, parent []step, defs *[]definition) {
+	m.Range(func(fd protoreflect.FieldDescriptor, val protoreflect.Value) bool {
+		if !fd.IsList() || !named(fd) {
+			return true
+		}
+
+		for i := range val.List().Len() {
+			el := val.List().Get(i).Message()
+			key := name(el)
+			s := step{string(fd.Name()), key}
+
+			*defs = append(*defs, definition{parent: parent, list: s.field, name: key})
+			defined(el, append(parent[:len(parent):len(parent)], s), defs)
+		}
+
+		return true
+	})
+}
+
+// listsOf collects the names of the lists of named objects reachable from md.
+func listsOf(md protoreflect.MessageDescriptor, lists map[string]bool, seen map[protoreflect.FullName]bool) {
+	if seen[md.FullName()] {
+		return
+	}
+
+	seen[md.FullName()] = true
+
+	fields := md.Fields()
+
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		if fd.Message() == nil {
+			continue
+		}
+
+		if fd.IsList() && named(fd) {
+			lists[string(fd.Name())] = true
+		}
+
+		listsOf(fd.Message(), lists, seen)
+	}
+}
+
+func wellKnown(md protoreflect.MessageDescriptor) bool {
+	switch md.FullName() {
+	case "google.protobuf.Any", "google.protobuf.Duration", "google.protobuf.FieldMask",
+		"google.protobuf.ListValue", "google.protobuf.Struct", "google.protobuf.Timestamp",
+		"google.protobuf.Value":
+		return true
+	}
+
+	return strings.HasSuffix(md.ParentFile().Path(), "google/protobuf/wrappers.proto")
+}
+
+func mapping(n ast.Node) ([]*ast.MappingValueNode, bool) {
+	switch n := unwrap(n).(type) {
+	case *ast.MappingNode:
+		return n.Values, true
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{n}, true
+	}
+
+	return nil, false
+}
+
+// unwrap returns the node under any tag or anchor.
+func unwrap(n ast.Node) ast.Node {
+	for {
+		switch t := n.(type) {
+		case *ast.TagNode:
+			n = t.Value
+		case *ast.AnchorNode:
+			n = t.Value
+		case *ast.MappingKeyNode:
+			n = t.Value
+		default:
+			return n
+		}
+	}
+}
+
+func text(n ast.Node) string {
+	if s, ok := unwrap(n).(ast.ScalarNode); ok {
+		return fmt.Sprint(s.GetValue())
+	}
+
+	return ""
+}
+
+func nameOf(n ast.Node) string {
+	pairs, _ := mapping(n)
+
+	for _, p := range pairs {
+		if text(p.Key) == "name" {
+			return text(p.Value)
+		}
+	}
+
+	return ""
+}
+
+func enumNames(ed protoreflect.EnumDescriptor) []string {
+	names := make([]string, ed.Values().Len())
+
+	for i := range names {
+		names[i] = string(ed.Values().Get(i).Name())
+	}
+
+	return names
+}
+
+func position(file string, n ast.Node, msg string) Error {
+	e := Error{File: file, Msg: msg}
+
+	if n != nil && n.GetToken() != nil {
+		e.Line = n.GetToken().Position.Line
+		e.Column = n.GetToken().Position.Column
+	}
+
+	return e
+}
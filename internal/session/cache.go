@@ -0,0 +1,104 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Token is a cached metal session for one server.
+type Token struct {
+	User    string    `yaml:"user"`
+	Value   string    `yaml:"value"`
+	Expires time.Time `yaml:"expires"`
+}
+
+// Cache is the on-disk set of session tokens keyed by server address.
+type Cache struct {
+	Tokens map[string]Token `yaml:"tokens,omitempty"`
+
+	path string
+}
+
+// expiryMargin keeps a token from being used right as it expires.
+const expiryMargin = time.Minute
+
+// DefaultCachePath returns the location of the token cache file.
+func DefaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "stack", "sessions.yaml")
+}
+
+// LoadCache reads the token cache at path. A missing file yields an empty
+// cache.
+func LoadCache(path string) (*Cache, error) {
+	c := Cache{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &c, nil
+		}
+
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+// Get returns the unexpired token for server.
+func (c *Cache) Get(server string) (Token, bool) {
+	t, ok := c.Tokens[server]
+	if !ok || time.Now().Add(expiryMargin).After(t.Expires) {
+		return Token{}, false
+	}
+
+	return t, true
+}
+
+// Put stores the token for server and saves the cache.
+func (c *Cache) Put(server string, t Token) error {
+	if c.Tokens == nil {
+		c.Tokens = make(map[string]Token)
+	}
+
+	c.Tokens[server] = t
+
+	return c.save()
+}
+
+// Delete removes the token for server and saves the cache.
+func (c *Cache) Delete(server string) error {
+	if _, ok := c.Tokens[server]; !ok {
+		return nil
+	}
+
+	delete(c.Tokens, server)
+
+	return c.save()
+}
+
+func (c *Cache) save() error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, data, 0o600)
+}
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// PasswordEnv names the environment variable checked for a password when the
+// context does not configure a credential source.
+const PasswordEnv = "STACK_PASSWORD"
+
+// ReadPassword reads the password from the first line of r.
+func ReadPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Prompt asks for the password on the terminal without echoing it. It fails
+// if stdin is not a terminal.
+func Prompt(user, server string) (string, error) {
+	fd := int(os.Stdin.Fd()) //nolint:gosec
+
+	if !term.IsTerminal(fd) {
+		return "", errNoPassword
+	}
+
+	fmt.Fprintf(os.Stderr, "Password for %s@%s: ", user, server)
+
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+
+	return string(pass), err
+}
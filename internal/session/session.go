@@ -0,0 +1,292 @@
+// Package session caches metal authorization tokens between invocations.
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Session ties a server and user to the token cache. It is a
+// grpc.PerRPCCredentials, so nothing is authorized until the first RPC needs
+// a token.
+type Session struct {
+	Server string
+	User   string
+	Cache  *Cache
+
+	// Authorize logs in to the server and returns a context carrying the
+	// resulting authorization metadata. It must not use a connection that
+	// has the Session as its credentials.
+	Authorize func(user, pass string) (context.Context, error)
+
+	// Password supplies the password when there is no cached token.
+	Password func() (string, error)
+
+	once  sync.Once
+	mu    sync.Mutex
+	value string
+	err   error
+}
+
+const (
+	authorization = "authorization"
+	defaultTTL    = time.Hour
+)
+
+var (
+	errNoPassword = errors.New("no password available, run \"stack login\"")
+	errNoToken    = errors.New("server did not return a session token")
+)
+
+// resume uses the cached token for the session's server, logging in again if
+// there is none or it has expired.
+func (s *Session) resume() error {
+	if t, ok := s.Cache.Get(s.Server); ok && t.User == s.User {
+		s.mu.Lock()
+		s.value = t.Value
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	if s.Password == nil {
+		return errNoPassword
+	}
+
+	pass, err := s.Password()
+	if err != nil {
+		return err
+	}
+
+	return s.Login(pass)
+}
+
+// Login authorizes with pass and caches the resulting token.
+func (s *Session) Login(pass string) error {
+	ctx, err := s.Authorize(s.User, pass)
+	if err != nil {
+		return err
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+
+	vals := md.Get(authorization)
+	if len(vals) == 0 {
+		return errNoToken
+	}
+
+	s.mu.Lock()
+	s.value = vals[0]
+	s.mu.Unlock()
+
+	return s.Cache.Put(s.Server, Token{
+		User:    s.User,
+		Value:   vals[0],
+		Expires: expiry(vals[0]),
+	})
+}
+
+// Logout forgets the cached token for the session's server.
+func (s *Session) Logout() error {
+	return s.Cache.Delete(s.Server)
+}
+
+// reauthorize drops a token the server rejected and logs in again.
+func (s *Session) reauthorize(rejected string) error {
+	s.mu.Lock()
+	current := s.value
+	s.mu.Unlock()
+
+	if current != rejected { // another call has already logged in again
+		return nil
+	}
+
+	if err := s.Cache.Delete(s.Server); err != nil {
+		return err
+	}
+
+	if s.Password == nil {
+		return errNoPassword
+	}
+
+	pass, err := s.Password()
+	if err != nil {
+		return err
+	}
+
+	return s.Login(pass)
+}
+
+// token returns the token sent with calls, empty if there is none yet.
+func (s *Session) token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.value
+}
+
+func (s *Session) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	s.once.Do(func() {
+		if s.token() == "" { // not already set by Login
+			s.err = s.resume()
+		}
+	})
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	value := s.token()
+	if value == "" {
+		return nil, nil
+	}
+
+	return map[string]string{authorization: value}, nil
+}
+
+// UnaryInterceptor retries a call once when the server rejects the token,
+// after logging in again. The server may drop a token before its expiry, on
+// a restart or when the token is revoked.
+func (s *Session) UnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		sent := s.token()
+		if sent == "" || status.Code(err) != codes.Unauthenticated {
+			return err
+		}
+
+		if err := s.reauthorize(sent); err != nil {
+			return err
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamInterceptor does for streams what UnaryInterceptor does for unary
+// calls. A stream is only retried if the server rejects it before sending
+// anything, by opening it again and sending the same requests.
+func (s *Session) StreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		open := func() (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		return &retryStream{ClientStream: cs, session: s, sent: s.token(), open: open}, nil
+	}
+}
+
+// retryStream keeps what was sent on a stream until the first response, so
+// the stream can be opened again if the server rejects its token.
+type retryStream struct {
+	grpc.ClientStream
+	session  *Session
+	sent     string
+	open     func() (grpc.ClientStream, error)
+	requests []any
+	closed   bool
+	received bool
+}
+
+func (r *retryStream) SendMsg(m any) error {
+	if !r.received {
+		r.requests = append(r.requests, m)
+	}
+
+	return r.ClientStream.SendMsg(m)
+}
+
+func (r *retryStream) CloseSend() error {
+	r.closed = true
+
+	return r.ClientStream.CloseSend()
+}
+
+func (r *retryStream) RecvMsg(m any) error {
+	err := r.ClientStream.RecvMsg(m)
+	if r.received || r.sent == "" || status.Code(err) != codes.Unauthenticated {
+		r.received = true
+		r.requests = nil
+
+		return err
+	}
+
+	r.received = true
+
+	if err := r.session.reauthorize(r.sent); err != nil {
+		return err
+	}
+
+	cs, err := r.open()
+	if err != nil {
+		return err
+	}
+
+	r.ClientStream = cs
+
+	for _, req := range r.requests {
+		if err := cs.SendMsg(req); err != nil {
+			return err
+		}
+	}
+
+	r.requests = nil
+
+	if r.closed {
+		if err := cs.CloseSend(); err != nil {
+			return err
+		}
+	}
+
+	return cs.RecvMsg(m)
+}
+
+func (*Session) RequireTransportSecurity() bool {
+	return true
+}
+
+// expiry reads the exp claim when the token is a JWT, and otherwise assumes
+// the default lifetime.
+func expiry(value string) time.Time {
+	fallback := time.Now().Add(defaultTTL)
+
+	parts := strings.Split(strings.TrimPrefix(value, "Bearer "), ".")
+	if len(parts) != 3 {
+		return fallback
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fallback
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return fallback
+	}
+
+	return time.Unix(claims.Exp, 0)
+}
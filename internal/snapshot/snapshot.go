@@ -0,0 +1,154 @@
+// Package snapshot keeps a local history of schema dumps.
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Snapshot is one saved schema along with where and when it was taken.
+type Snapshot struct {
+	Name   string          `json:"name"`
+	Time   time.Time       `json:"time"`
+	Server string          `json:"server"`
+	User   string          `json:"user"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// Store is a directory of gzipped JSON snapshots.
+type Store struct {
+	dir string
+}
+
+const (
+	ext        = ".json.gz"
+	timeFormat = "20060102T150405Z"
+)
+
+var (
+	errName     = errors.New("invalid snapshot name")
+	errNotFound = errors.New("snapshot not found")
+)
+
+// DefaultDir returns the snapshot directory, honoring XDG_DATA_HOME.
+func DefaultDir() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+
+		dir = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dir, "stack", "snapshots")
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Save writes s. An empty name is replaced by the snapshot's time, which is
+// set to now.
+func (st *Store) Save(s *Snapshot) error {
+	s.Time = time.Now().UTC()
+
+	id := s.Time.Format(timeFormat)
+	if s.Name == "" {
+		s.Name = id
+	}
+
+	if strings.ContainsAny(s.Name, `/\`) {
+		return fmt.Errorf("%w %q", errName, s.Name)
+	}
+
+	if err := os.MkdirAll(st.dir, 0o700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(st.dir, id+"-"+s.Name+ext), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+
+	if err := json.NewEncoder(zw).Encode(s); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// List returns the snapshots, oldest first.
+func (st *Store) List() ([]Snapshot, error) {
+	files, err := filepath.Glob(filepath.Join(st.dir, "*"+ext))
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(files) // the names start with the time
+
+	list := make([]Snapshot, 0, len(files))
+
+	for _, file := range files {
+		s, err := read(file)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, s)
+	}
+
+	return list, nil
+}
+
+// Get returns the newest snapshot with the given name.
+func (st *Store) Get(name string) (Snapshot, error) {
+	list, err := st.List()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Name == name {
+			return list[i], nil
+		}
+	}
+
+	return Snapshot{}, fmt.Errorf("%w: %s", errNotFound, name)
+}
+
+func read(file string) (Snapshot, error) {
+	var s Snapshot
+
+	f, err := os.Open(file)
+	if err != nil {
+		return s, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return s, fmt.Errorf("%s: %w", file, err)
+	}
+
+	if err := json.NewDecoder(zr).Decode(&s); err != nil {
+		return s, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return s, nil
+}
@@ -2,20 +2,27 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
 	"net/http"
 	"os"
+	"os/user"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 
 	"endobit.io/metal"
 	"endobit.io/metal/logging"
 	"endobit.io/mops"
+	"endobit.io/stack/internal/audit"
 	"endobit.io/stack/internal/commands"
+	"endobit.io/stack/internal/config"
+	"endobit.io/stack/internal/flags"
+	"endobit.io/stack/internal/session"
 )
 
 var version string
@@ -31,72 +38,288 @@ func main() {
 
 func newRootCmd() *cobra.Command {
 	var (
-		metalUser, metalPass, metalServer string
-		metalClient                       metal.Client
-		mopsServer                        string
-		mopsClient                        mops.Client
-		logOpts                           *logging.Options
+		metalUser, metalServer  string
+		metalClient             metal.Client
+		mopsServer              string
+		auditLog                string
+		auditor                 *audit.Log
+		mopsClient              mops.Client
+		configPath, contextName string
+		tlsOpts                 config.TLS
+		logOpts                 *logging.Options
+		sess                    session.Session
+		root                    commands.Root
 	)
 
-	cmd := cobra.Command{
-		Use:   "stack",
-		Short: "Stack Client",
-		Long:  "Stack Command Line Client",
-		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
-			logger, err := logOpts.NewLogger()
-			if err != nil {
+	readConfig := func() error {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return err
+		}
+
+		root.Config = cfg
+
+		return nil
+	}
+
+	loadConfig := func() (*config.Context, error) {
+		if err := readConfig(); err != nil {
+			return nil, err
+		}
+
+		profile, err := root.Config.Context(contextName)
+		if err != nil {
+			return nil, err
+		}
+
+		root.Profile = profile
+
+		return profile, nil
+	}
+
+	setup := func(cmd *cobra.Command) error {
+		profile, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		fs := cmd.Flags()
+
+		inherit(fs, flags.Metal, &metalServer, profile.Metal)
+		inherit(fs, flags.Mops, &mopsServer, profile.Mops)
+		inherit(fs, flags.MetalUser, &metalUser, profile.User)
+		inherit(fs, flags.CACert, &tlsOpts.CACert, profile.TLS.CACert)
+		inherit(fs, flags.ClientCert, &tlsOpts.ClientCert, profile.TLS.ClientCert)
+		inherit(fs, flags.ClientKey, &tlsOpts.ClientKey, profile.TLS.ClientKey)
+		inherit(fs, flags.ServerName, &tlsOpts.ServerName, profile.TLS.ServerName)
+		inherit(fs, flags.Insecure, &tlsOpts.Insecure, profile.TLS.Insecure)
+		inherit(fs, flags.AuditLog, &auditLog, profile.AuditLog)
+
+		if f := fs.Lookup(flags.Zone); f != nil && !f.Changed && profile.Zone != "" {
+			if err := fs.Set(flags.Zone, profile.Zone); err != nil {
 				return err
 			}
+		}
+
+		logger, err := logOpts.NewLogger()
+		if err != nil {
+			return err
+		}
+
+		tlsConfig, err := tlsOpts.Config()
+		if err != nil {
+			return err
+		}
+
+		cache, err := session.LoadCache(session.DefaultCachePath())
+		if err != nil {
+			return err
+		}
+
+		// Neither client touches the network here. grpc.NewClient connects on
+		// the first RPC, at which point the session supplies a token, and the
+		// mops client is plain HTTP. Commands that never call a backend, or
+		// only call one, work without the other being reachable.
+		transport := grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+
+		authConn, err := grpc.NewClient(metalServer, transport)
+		if err != nil {
+			return err
+		}
+
+		authClient := metal.NewClient(authConn, logger)
+
+		sess = session.Session{
+			Server: metalServer,
+			User:   metalUser,
+			Cache:  cache,
+			Authorize: func(user, pass string) (context.Context, error) {
+				if err := authClient.Authorize(user, pass); err != nil {
+					return nil, err
+				}
 
-			creds := credentials.NewTLS(&tls.Config{
-				InsecureSkipVerify: true, //nolint:gosec
-				MinVersion:         tls.VersionTLS12,
-			})
+				return authClient.Context(), nil
+			},
+			Password: func() (string, error) {
+				if pass, ok, err := profile.Credential.Password(); ok || err != nil {
+					return pass, err
+				}
 
-			conn, err := grpc.NewClient(metalServer, grpc.WithTransportCredentials(creds))
+				if pass, ok := os.LookupEnv(session.PasswordEnv); ok {
+					return pass, nil
+				}
+
+				return session.Prompt(metalUser, metalServer)
+			},
+		}
+
+		opts := []grpc.DialOption{
+			transport,
+			grpc.WithPerRPCCredentials(&sess),
+			grpc.WithChainUnaryInterceptor(sess.UnaryInterceptor()),
+			grpc.WithChainStreamInterceptor(sess.StreamInterceptor()),
+		}
+
+		if auditLog != "" {
+			log, err := audit.Open(auditLog)
 			if err != nil {
 				return err
 			}
 
-			metalClient = *metal.NewClient(conn, logger)
-			if err := metalClient.Authorize(metalUser, metalPass); err != nil {
-				return err
+			log.User = metalUser
+			log.Context = profile.Name
+			log.Server = metalServer
+			log.Command = os.Args
+
+			if u, err := user.Current(); err == nil {
+				log.OSUser = u.Username
 			}
 
-			mopsClient = mops.Client{
-				URL: "http://" + mopsServer,
-				Client: http.Client{
-					Timeout: 5 * time.Second,
+			auditor = log
+			opts = append(opts, grpc.WithChainUnaryInterceptor(log.Interceptor()))
+		}
+
+		conn, err := grpc.NewClient(metalServer, opts...)
+		if err != nil {
+			return err
+		}
+
+		metalClient = *metal.NewClient(conn, logger)
+
+		mopsTLS := tlsConfig.Clone()
+		mopsTLS.ServerName = "" // --server-name only applies to metal
+
+		mopsClient = mops.Client{
+			URL: mopsURL(mopsServer),
+			Client: http.Client{
+				Timeout: 5 * time.Second,
+				Transport: &http.Transport{
+					Proxy:           http.ProxyFromEnvironment,
+					TLSClientConfig: mopsTLS,
 				},
+			},
+		}
+
+		return nil
+	}
+
+	cmd := cobra.Command{
+		Use:   "stack",
+		Short: "Stack Client",
+		Long:  "Stack Command Line Client",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if offline(cmd) {
+				return nil
+			}
+
+			return setup(cmd)
+		},
+		PersistentPostRunE: func(_ *cobra.Command, _ []string) error {
+			if auditor == nil {
+				return nil
 			}
 
-			return nil
+			return auditor.Close()
 		},
 	}
 
 	logOpts = logging.NewOptions(cmd.PersistentFlags())
 
-	cmd.PersistentFlags().StringVar(&metalUser, "metal-user", "admin", "username for metal authentication")
-	cmd.PersistentFlags().StringVar(&metalPass, "metal-pass", "admin", "password for metal authentication")
-	cmd.PersistentFlags().StringVar(&metalServer, "metal", "localhost:"+strconv.Itoa(metal.DefaultPort),
+	cmd.PersistentFlags().StringVar(&metalUser, flags.MetalUser, "admin", "username for metal authentication")
+	cmd.PersistentFlags().StringVar(&metalServer, flags.Metal, "localhost:"+strconv.Itoa(metal.DefaultPort),
 		"address of the metal server")
-	cmd.PersistentFlags().StringVar(&mopsServer, "mops", "localhost:"+strconv.Itoa(mops.DefaultPort),
-		"address of the mops server")
+	cmd.PersistentFlags().StringVar(&mopsServer, flags.Mops, "localhost:"+strconv.Itoa(mops.DefaultPort),
+		"address of the mops server, prefix with https:// to use TLS")
+	cmd.PersistentFlags().StringVar(&tlsOpts.CACert, flags.CACert, "", "CA bundle for verifying server certificates")
+	cmd.PersistentFlags().StringVar(&tlsOpts.ClientCert, flags.ClientCert, "", "client certificate for mutual TLS")
+	cmd.PersistentFlags().StringVar(&tlsOpts.ClientKey, flags.ClientKey, "", "client key for mutual TLS")
+	cmd.PersistentFlags().StringVar(&tlsOpts.ServerName, flags.ServerName, "",
+		"server name to verify the metal certificate against")
+	cmd.PersistentFlags().BoolVar(&tlsOpts.Insecure, flags.Insecure, false,
+		"skip server certificate verification (insecure)")
+	cmd.PersistentFlags().StringVar(&auditLog, flags.AuditLog, "",
+		"append mutating calls to this file as JSON lines, or send them to "+audit.Syslog)
+	cmd.PersistentFlags().StringVar(&configPath, flags.Config, config.DefaultPath(), "client configuration file")
+	cmd.PersistentFlags().StringVar(&contextName, flags.Context, "", "configuration context to use")
+
+	root.Metal = &metalClient
+	root.Ops = &mopsClient
+	root.Session = &sess
+
+	// config commands fix the contexts, so they must work when the current
+	// or --context one does not resolve
+	configCmd := root.New(commands.Config)
+	configCmd.PersistentPreRunE = func(_ *cobra.Command, _ []string) error {
+		return readConfig()
+	}
 
-	root := commands.Root{
-		Metal: &metalClient,
-		Ops:   &mopsClient,
+	// schema and validate without --live work on files alone, so they need
+	// no context and no clients
+	schemaCmd := root.New(commands.Schema)
+	schemaCmd.PersistentPreRunE = func(_ *cobra.Command, _ []string) error {
+		return nil
+	}
+
+	validateCmd := root.New(commands.Validate)
+	validateCmd.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
+		if live, _ := cmd.Flags().GetBool(flags.Live); live {
+			return setup(cmd)
+		}
+
+		return nil
 	}
 
 	cmd.AddCommand(
 		root.New(commands.Add),
+		root.New(commands.Apply),
+		configCmd,
+		root.New(commands.Diff),
 		root.New(commands.Dump),
 		root.New(commands.List),
 		root.New(commands.Load),
+		root.New(commands.Login),
+		root.New(commands.Logout),
 		root.New(commands.Remove),
 		root.New(commands.Report),
+		schemaCmd,
 		root.New(commands.Set),
-		root.New(commands.Unset))
+		root.New(commands.Snapshot),
+		root.New(commands.Undo),
+		root.New(commands.Unset),
+		validateCmd)
 
 	return &cmd
 }
+
+// offline reports whether cmd is one of cobra's help and completion
+// commands, which never call a backend.
+func offline(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return true
+		}
+	}
+
+	return false
+}
+
+// inherit sets dst from the context value src unless the flag was given on the
+// command line or src is unset.
+func inherit[T comparable](fs *pflag.FlagSet, flag string, dst *T, src T) {
+	var zero T
+
+	if !fs.Changed(flag) && src != zero {
+		*dst = src
+	}
+}
+
+// mopsURL returns the base URL for the mops server. Addresses without a
+// scheme use plain http.
+func mopsURL(addr string) string {
+	if strings.Contains(addr, "://") {
+		return addr
+	}
+
+	return "http://" + addr
+}